@@ -0,0 +1,95 @@
+package apikey
+
+import (
+	"crypto/subtle"
+	"fmt"
+
+	"github.com/Lumicrate/gompose/auth"
+	"github.com/Lumicrate/gompose/http"
+)
+
+// Validator resolves an API key to the ID of the service it belongs to.
+type Validator func(key string) (subject string, ok bool)
+
+type APIKeyProvider struct {
+	HeaderName string
+	Validate   Validator
+}
+
+func NewAPIKeyProvider(validate Validator) *APIKeyProvider {
+	return &APIKeyProvider{
+		HeaderName: "X-API-Key",
+		Validate:   validate,
+	}
+}
+
+// NewStaticAPIKeyProvider accepts a fixed set of keys, mapping each key to
+// the subject it authenticates.
+func NewStaticAPIKeyProvider(keys map[string]string) *APIKeyProvider {
+	return NewAPIKeyProvider(func(key string) (string, bool) {
+		for k, subject := range keys {
+			if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+				return subject, true
+			}
+		}
+		return "", false
+	})
+}
+
+func (a *APIKeyProvider) SetHeaderName(name string) *APIKeyProvider {
+	a.HeaderName = name
+	return a
+}
+
+func (a *APIKeyProvider) Init() error {
+	if a.HeaderName == "" {
+		return fmt.Errorf("apikey: HeaderName must be provided")
+	}
+
+	if a.Validate == nil {
+		return fmt.Errorf("apikey: Validate must be provided")
+	}
+
+	return nil
+}
+
+func (a *APIKeyProvider) Close() error {
+	return nil
+}
+
+func (a *APIKeyProvider) RegisterRoutes(_ http.HTTPEngine) {}
+
+func (a *APIKeyProvider) Name() string {
+	return "apikey"
+}
+
+func (a *APIKeyProvider) Authenticate(ctx http.Context) (string, error) {
+	key := ctx.Header(a.HeaderName)
+	if key == "" {
+		return "", fmt.Errorf("missing %s header", a.HeaderName)
+	}
+
+	subject, ok := a.Validate(key)
+	if !ok {
+		return "", fmt.Errorf("invalid API key")
+	}
+
+	return subject, nil
+}
+
+func (a *APIKeyProvider) Middleware() http.MiddlewareFunc {
+	return auth.Middleware(a)
+}
+
+func (a *APIKeyProvider) SecuritySchemes() []auth.SecurityScheme {
+	return []auth.SecurityScheme{{
+		Name:      "ApiKeyAuth",
+		Type:      "apiKey",
+		In:        "header",
+		ParamName: a.HeaderName,
+	}}
+}
+
+func (a *APIKeyProvider) SecurityRequirements() [][]string {
+	return [][]string{{"ApiKeyAuth"}}
+}
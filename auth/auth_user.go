@@ -5,3 +5,10 @@ type AuthUser interface {
 	GetEmail() string
 	GetHashedPassword() string
 }
+
+// VerifiableUser is implemented by user models that track whether the
+// email address has been verified. The flag is expected in a bool field
+// named EmailVerified.
+type VerifiableUser interface {
+	IsEmailVerified() bool
+}
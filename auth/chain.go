@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Lumicrate/gompose/http"
+)
+
+type ChainMode int
+
+const (
+	// FirstSuccess accepts the request as soon as one provider authenticates it.
+	FirstSuccess ChainMode = iota
+	// RequireAll accepts the request only if every provider authenticates it.
+	RequireAll
+)
+
+// ChainProvider combines several providers behind a single AuthProvider.
+// Every provider in the chain must implement Authenticator.
+type ChainProvider struct {
+	mode      ChainMode
+	providers []AuthProvider
+}
+
+// AnyOf returns a provider that authenticates a request with the first
+// provider that accepts it, e.g. a JWT for browsers or an API key for services.
+func AnyOf(providers ...AuthProvider) *ChainProvider {
+	return &ChainProvider{mode: FirstSuccess, providers: providers}
+}
+
+// AllOf returns a provider that requires every provider to accept the request.
+func AllOf(providers ...AuthProvider) *ChainProvider {
+	return &ChainProvider{mode: RequireAll, providers: providers}
+}
+
+func (c *ChainProvider) Init() error {
+	if len(c.providers) == 0 {
+		return fmt.Errorf("auth: chain needs at least one provider")
+	}
+
+	for _, p := range c.providers {
+		if _, ok := p.(Authenticator); !ok {
+			return fmt.Errorf("auth: provider %T does not implement Authenticator and cannot be chained", p)
+		}
+		if err := p.Init(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close closes the providers in reverse order and returns all their errors.
+func (c *ChainProvider) Close() error {
+	var errs []error
+	for i := len(c.providers) - 1; i >= 0; i-- {
+		if err := c.providers[i].Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *ChainProvider) RegisterRoutes(engine http.HTTPEngine) {
+	for _, p := range c.providers {
+		p.RegisterRoutes(engine)
+	}
+}
+
+func (c *ChainProvider) Name() string {
+	names := make([]string, len(c.providers))
+	for i, p := range c.providers {
+		names[i] = p.(Authenticator).Name()
+	}
+
+	sep := "|"
+	if c.mode == RequireAll {
+		sep = "+"
+	}
+	return strings.Join(names, sep)
+}
+
+// Authenticate runs the chain and returns the subject ID. It also records the
+// providers that accepted the request under CtxAuthProvider (the first one)
+// and CtxAuthProviders (all of them).
+func (c *ChainProvider) Authenticate(ctx http.Context) (string, error) {
+	var (
+		subject  string
+		accepted []string
+		errs     []error
+	)
+
+	for _, p := range c.providers {
+		a := p.(Authenticator)
+		id, err := a.Authenticate(ctx)
+		if err != nil {
+			if c.mode == RequireAll {
+				return "", fmt.Errorf("%s: %w", a.Name(), err)
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", a.Name(), err))
+			continue
+		}
+
+		if subject == "" {
+			subject = id
+		}
+		accepted = append(accepted, a.Name())
+
+		if c.mode == FirstSuccess {
+			break
+		}
+	}
+
+	if len(accepted) == 0 {
+		msgs := make([]string, len(errs))
+		for i, err := range errs {
+			msgs[i] = err.Error()
+		}
+		return "", errors.New(strings.Join(msgs, "; "))
+	}
+
+	ctx.Set(CtxAuthProvider, accepted[0])
+	ctx.Set(CtxAuthProviders, accepted)
+
+	return subject, nil
+}
+
+func (c *ChainProvider) Middleware() http.MiddlewareFunc {
+	return Middleware(c)
+}
+
+func (c *ChainProvider) SecuritySchemes() []SecurityScheme {
+	var schemes []SecurityScheme
+	seen := map[string]bool{}
+
+	for _, p := range c.providers {
+		d, ok := p.(SecurityDescriber)
+		if !ok {
+			continue
+		}
+		for _, s := range d.SecuritySchemes() {
+			if !seen[s.Name] {
+				seen[s.Name] = true
+				schemes = append(schemes, s)
+			}
+		}
+	}
+
+	return schemes
+}
+
+func (c *ChainProvider) SecurityRequirements() [][]string {
+	var result [][]string
+
+	for _, p := range c.providers {
+		d, ok := p.(SecurityDescriber)
+		if !ok {
+			continue
+		}
+		reqs := d.SecurityRequirements()
+
+		if c.mode == FirstSuccess {
+			result = append(result, reqs...)
+			continue
+		}
+
+		// RequireAll: every alternative so far must be combined with every
+		// alternative of this provider.
+		if result == nil {
+			result = reqs
+			continue
+		}
+		var combined [][]string
+		for _, left := range result {
+			for _, right := range reqs {
+				merged := append(append([]string{}, left...), right...)
+				combined = append(combined, merged)
+			}
+		}
+		result = combined
+	}
+
+	return result
+}
+
+// Middleware turns an Authenticator into an http.MiddlewareFunc that rejects
+// unauthenticated requests with 401 and stores the subject under CtxUserID.
+// CORS preflights pass unauthenticated, since browsers never send
+// credentials with them; they only reach the OPTIONS route.
+func Middleware(a Authenticator) http.MiddlewareFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(ctx http.Context) {
+			if http.IsPreflight(ctx) {
+				next(ctx)
+				return
+			}
+
+			subject, err := a.Authenticate(ctx)
+			if err != nil {
+				ctx.JSON(401, map[string]string{"error": err.Error()})
+				ctx.Abort()
+				return
+			}
+
+			if ctx.Get(CtxAuthProvider) == nil {
+				ctx.Set(CtxAuthProvider, a.Name())
+				ctx.Set(CtxAuthProviders, []string{a.Name()})
+			}
+			ctx.Set(CtxUserID, subject)
+			next(ctx)
+		}
+	}
+}
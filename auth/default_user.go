@@ -1,11 +1,13 @@
 package auth
 
 type UserModel struct {
-	ID       string `gorm:"primaryKey" json:"id" bson:"id,omitempty"`
-	Email    string `gorm:"unique" json:"email" bson:"email"`
-	Password string `json:"password" bson:"password"`
+	ID            string `gorm:"primaryKey" json:"id" bson:"id,omitempty"`
+	Email         string `gorm:"unique" json:"email" bson:"email"`
+	Password      string `json:"password" bson:"password"`
+	EmailVerified bool   `json:"email_verified" bson:"email_verified"`
 }
 
 func (u *UserModel) GetID() string             { return u.ID }
 func (u *UserModel) GetEmail() string          { return u.Email }
 func (u *UserModel) GetHashedPassword() string { return u.Password }
+func (u *UserModel) IsEmailVerified() bool     { return u.EmailVerified }
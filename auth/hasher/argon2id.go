@@ -0,0 +1,107 @@
+package hasher
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"math"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// maxArgon2Memory is the largest memory parameter accepted in a stored
+// hash, 4 GiB in KiB.
+const maxArgon2Memory = 4 * 1024 * 1024
+
+type Argon2id struct {
+	Memory      uint32 // in KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  int
+	KeyLength   uint32
+}
+
+// NewArgon2id returns an argon2id hasher with the parameters recommended by
+// OWASP (19 MiB of memory, 2 iterations, 1 degree of parallelism).
+func NewArgon2id() *Argon2id {
+	return &Argon2id{
+		Memory:      19 * 1024,
+		Iterations:  2,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+func (a *Argon2id) Hash(password string) (string, error) {
+	salt, err := newSalt(a.SaltLength)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, a.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, a.Memory, a.Iterations, a.Parallelism,
+		b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+}
+
+func (a *Argon2id) Verify(hash, password string) (bool, error) {
+	p, salt, key, err := a.decode(hash)
+	if err != nil {
+		return false, err
+	}
+
+	actual := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(actual, key) == 1, nil
+}
+
+func (a *Argon2id) NeedsRehash(hash string) bool {
+	p, salt, key, err := a.decode(hash)
+	if err != nil {
+		return true
+	}
+
+	return p.Memory != a.Memory || p.Iterations != a.Iterations || p.Parallelism != a.Parallelism ||
+		len(salt) != a.SaltLength || uint32(len(key)) != a.KeyLength
+}
+
+func (a *Argon2id) Identifies(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
+
+func (a *Argon2id) decode(hash string) (*Argon2id, []byte, []byte, error) {
+	params, salt, key, err := parsePHC(hash, "argon2id")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if params["v"] != fmt.Sprint(argon2.Version) {
+		return nil, nil, nil, fmt.Errorf("hasher: unsupported argon2 version %q", params["v"])
+	}
+
+	m, err := paramInt(params, "m")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	t, err := paramInt(params, "t")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	p, err := paramInt(params, "p")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Reject parameters that argon2 cannot use, that make the hash
+	// trivially weak or that would have Verify allocate more than
+	// maxArgon2Memory, rather than computing a key from them.
+	if m < 1 || m > maxArgon2Memory || t < 1 || t > math.MaxUint32 || p < 1 || p > math.MaxUint8 {
+		return nil, nil, nil, ErrMalformedHash
+	}
+	if len(salt) == 0 || len(key) < 16 {
+		return nil, nil, nil, ErrMalformedHash
+	}
+
+	return &Argon2id{Memory: uint32(m), Iterations: uint32(t), Parallelism: uint8(p)}, salt, key, nil
+}
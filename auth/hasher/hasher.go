@@ -0,0 +1,130 @@
+// Package hasher provides auth.PasswordHasher implementations. Argon2id and
+// scrypt hashes are encoded as PHC strings; bcrypt keeps its own modular
+// crypt format ($2a$...), which PHC treats as a legacy format.
+package hasher
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Lumicrate/gompose/auth"
+)
+
+var (
+	b64 = base64.RawStdEncoding
+
+	ErrUnknownHash   = errors.New("hasher: unknown hash format")
+	ErrMalformedHash = errors.New("hasher: malformed hash")
+)
+
+// Identifier is implemented by hashers that can tell whether a hash was
+// produced by their algorithm.
+type Identifier interface {
+	Identifies(hash string) bool
+}
+
+// Upgrade hashes new passwords with primary and still verifies hashes made
+// by any of the legacy hashers. Those hashes are reported by NeedsRehash so
+// that they are replaced on the next successful login.
+func Upgrade(primary auth.PasswordHasher, legacy ...auth.PasswordHasher) auth.PasswordHasher {
+	return &upgrade{primary: primary, legacy: legacy}
+}
+
+type upgrade struct {
+	primary auth.PasswordHasher
+	legacy  []auth.PasswordHasher
+}
+
+func (u *upgrade) Hash(password string) (string, error) {
+	return u.primary.Hash(password)
+}
+
+func (u *upgrade) Verify(hash, password string) (bool, error) {
+	h := u.hasherFor(hash)
+	if h == nil {
+		return false, ErrUnknownHash
+	}
+	return h.Verify(hash, password)
+}
+
+func (u *upgrade) NeedsRehash(hash string) bool {
+	if identifies(u.primary, hash) {
+		return u.primary.NeedsRehash(hash)
+	}
+	return true
+}
+
+func (u *upgrade) hasherFor(hash string) auth.PasswordHasher {
+	if identifies(u.primary, hash) {
+		return u.primary
+	}
+	for _, h := range u.legacy {
+		if identifies(h, hash) {
+			return h
+		}
+	}
+	return nil
+}
+
+func identifies(h auth.PasswordHasher, hash string) bool {
+	if id, ok := h.(Identifier); ok {
+		return id.Identifies(hash)
+	}
+	// Without a way to tell, assume the hasher can handle the hash.
+	return true
+}
+
+func newSalt(length int) ([]byte, error) {
+	salt := make([]byte, length)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// parsePHC splits "$id$v=19$k=v,k=v$salt$hash" into its parameters, salt and
+// hash. The version segment is optional.
+func parsePHC(hash, id string) (params map[string]string, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) < 5 || parts[0] != "" || parts[1] != id {
+		return nil, nil, nil, ErrMalformedHash
+	}
+
+	params = map[string]string{}
+	rest := parts[2:]
+	if strings.HasPrefix(rest[0], "v=") {
+		params["v"] = strings.TrimPrefix(rest[0], "v=")
+		rest = rest[1:]
+	}
+	if len(rest) != 3 {
+		return nil, nil, nil, ErrMalformedHash
+	}
+
+	for _, kv := range strings.Split(rest[0], ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, nil, nil, ErrMalformedHash
+		}
+		params[k] = v
+	}
+
+	if salt, err = b64.DecodeString(rest[1]); err != nil {
+		return nil, nil, nil, ErrMalformedHash
+	}
+	if key, err = b64.DecodeString(rest[2]); err != nil {
+		return nil, nil, nil, ErrMalformedHash
+	}
+
+	return params, salt, key, nil
+}
+
+func paramInt(params map[string]string, name string) (int, error) {
+	var v int
+	if _, err := fmt.Sscanf(params[name], "%d", &v); err != nil {
+		return 0, ErrMalformedHash
+	}
+	return v, nil
+}
@@ -0,0 +1,202 @@
+package hasher_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Lumicrate/gompose/auth"
+	"github.com/Lumicrate/gompose/auth/hasher"
+	"github.com/Lumicrate/gompose/db/sqlite"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Cheap parameters keep the tests fast; they are not for production.
+func fastArgon2id() *hasher.Argon2id {
+	return &hasher.Argon2id{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+}
+
+func fastScrypt() *hasher.Scrypt {
+	return &hasher.Scrypt{LogN: 4, R: 8, P: 1, SaltLength: 16, KeyLength: 32}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		hasher auth.PasswordHasher
+		prefix string
+	}{
+		{"bcrypt", hasher.NewBcrypt(bcrypt.MinCost), "$2a$"},
+		{"argon2id", fastArgon2id(), "$argon2id$v=19$m=64,t=1,p=1$"},
+		{"scrypt", fastScrypt(), "$scrypt$ln=4,r=8,p=1$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := tt.hasher.Hash("correct horse")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(hash, tt.prefix) {
+				t.Errorf("hash %s does not start with %s", hash, tt.prefix)
+			}
+
+			if ok, err := tt.hasher.Verify(hash, "correct horse"); !ok || err != nil {
+				t.Errorf("Verify(right password) = %v, %v", ok, err)
+			}
+			if ok, err := tt.hasher.Verify(hash, "wrong horse"); ok || err != nil {
+				t.Errorf("Verify(wrong password) = %v, %v", ok, err)
+			}
+			if tt.hasher.NeedsRehash(hash) {
+				t.Error("NeedsRehash on a fresh hash")
+			}
+
+			// Two hashes of the same password use different salts.
+			again, _ := tt.hasher.Hash("correct horse")
+			if again == hash {
+				t.Error("hashes are not salted")
+			}
+		})
+	}
+}
+
+func TestNeedsRehashOnChangedParameters(t *testing.T) {
+	argon := fastArgon2id()
+	argonHash, _ := argon.Hash("pw")
+	argon.Iterations = 2
+	if !argon.NeedsRehash(argonHash) {
+		t.Error("argon2id: NeedsRehash false after raising the iterations")
+	}
+
+	scr := fastScrypt()
+	scryptHash, _ := scr.Hash("pw")
+	scr.LogN = 5
+	if !scr.NeedsRehash(scryptHash) {
+		t.Error("scrypt: NeedsRehash false after raising N")
+	}
+
+	bc := hasher.NewBcrypt(bcrypt.MinCost)
+	bcryptHash, _ := bc.Hash("pw")
+	if !hasher.NewBcrypt(bcrypt.MinCost + 1).NeedsRehash(bcryptHash) {
+		t.Error("bcrypt: NeedsRehash false after raising the cost")
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	const (
+		salt = "c2FsdHNhbHRzYWx0c2FsdA"                      // 16 bytes
+		key  = "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U" // 32 bytes
+	)
+
+	tests := []struct {
+		name   string
+		hasher auth.PasswordHasher
+		hash   string
+	}{
+		{"argon2id empty", fastArgon2id(), ""},
+		{"argon2id wrong id", fastArgon2id(), "$argon2i$v=19$m=64,t=1,p=1$" + salt + "$" + key},
+		{"argon2id missing segment", fastArgon2id(), "$argon2id$v=19$m=64,t=1,p=1$" + salt},
+		{"argon2id bad base64", fastArgon2id(), "$argon2id$v=19$m=64,t=1,p=1$!!!$" + key},
+		{"argon2id missing param", fastArgon2id(), "$argon2id$v=19$m=64,t=1$" + salt + "$" + key},
+		{"argon2id non-numeric param", fastArgon2id(), "$argon2id$v=19$m=lots,t=1,p=1$" + salt + "$" + key},
+		{"argon2id zero iterations", fastArgon2id(), "$argon2id$v=19$m=64,t=0,p=1$" + salt + "$" + key},
+		{"argon2id huge parallelism", fastArgon2id(), "$argon2id$v=19$m=64,t=1,p=256$" + salt + "$" + key},
+		{"argon2id memory over cap", fastArgon2id(), "$argon2id$v=19$m=4194305,t=1,p=1$" + salt + "$" + key},
+		{"argon2id empty salt", fastArgon2id(), "$argon2id$v=19$m=64,t=1,p=1$$" + key},
+		{"argon2id short key", fastArgon2id(), "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$a2V5"},
+		{"scrypt wrong id", fastScrypt(), "$scrypt2$ln=4,r=8,p=1$" + salt + "$" + key},
+		{"scrypt zero ln", fastScrypt(), "$scrypt$ln=0,r=8,p=1$" + salt + "$" + key},
+		{"scrypt ln over cap", fastScrypt(), "$scrypt$ln=21,r=8,p=1$" + salt + "$" + key},
+		{"scrypt zero r", fastScrypt(), "$scrypt$ln=4,r=0,p=1$" + salt + "$" + key},
+		{"scrypt negative p", fastScrypt(), "$scrypt$ln=4,r=8,p=-1$" + salt + "$" + key},
+		{"scrypt r*p over cap", fastScrypt(), "$scrypt$ln=4,r=1073741824,p=1$" + salt + "$" + key},
+		{"scrypt r*p overflow", fastScrypt(), "$scrypt$ln=4,r=4611686018427387904,p=4$" + salt + "$" + key},
+		{"scrypt empty salt", fastScrypt(), "$scrypt$ln=4,r=8,p=1$$" + key},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := tt.hasher.Verify(tt.hash, "pw")
+			if ok || !errors.Is(err, hasher.ErrMalformedHash) {
+				t.Errorf("Verify = %v, %v; want ErrMalformedHash", ok, err)
+			}
+			if !tt.hasher.NeedsRehash(tt.hash) {
+				t.Error("NeedsRehash false for a malformed hash")
+			}
+		})
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	primary, legacy := fastArgon2id(), hasher.NewBcrypt(bcrypt.MinCost)
+	h := hasher.Upgrade(primary, legacy)
+
+	hash, _ := h.Hash("pw")
+	if !primary.Identifies(hash) {
+		t.Errorf("Upgrade hashed with another algorithm: %s", hash)
+	}
+	if h.NeedsRehash(hash) {
+		t.Error("NeedsRehash on a primary hash")
+	}
+
+	old, _ := legacy.Hash("pw")
+	if ok, err := h.Verify(old, "pw"); !ok || err != nil {
+		t.Errorf("Verify(legacy hash) = %v, %v", ok, err)
+	}
+	if !h.NeedsRehash(old) {
+		t.Error("NeedsRehash false for a legacy hash")
+	}
+
+	if _, err := h.Verify("$scrypt$ln=4,r=8,p=1$c2FsdA$a2V5", "pw"); !errors.Is(err, hasher.ErrUnknownHash) {
+		t.Errorf("Verify(unknown hash) error = %v, want ErrUnknownHash", err)
+	}
+}
+
+// TestRehashAfterLogin checks the login path end to end: a bcrypt hash is
+// replaced by the primary algorithm once the password has been verified.
+func TestRehashAfterLogin(t *testing.T) {
+	adapter := sqlite.NewMemory()
+	if err := adapter.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = adapter.Close() })
+	if err := adapter.Migrate([]any{&auth.UserModel{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	legacy := hasher.NewBcrypt(bcrypt.MinCost)
+	old, _ := legacy.Hash("pw")
+	if err := adapter.Create(&auth.UserModel{ID: "1", Email: "a@b.c", Password: old}); err != nil {
+		t.Fatal(err)
+	}
+
+	h := hasher.Upgrade(fastArgon2id(), legacy)
+	user, err := auth.FindUserByEmail(adapter, &auth.UserModel{}, "a@b.c")
+	if err != nil || user == nil {
+		t.Fatalf("FindUserByEmail = %v, %v", user, err)
+	}
+	if ok, _ := h.Verify(user.GetHashedPassword(), "pw"); !ok {
+		t.Fatal("legacy password did not verify")
+	}
+	if err := auth.RehashIfNeeded(adapter, h, user, "pw"); err != nil {
+		t.Fatal(err)
+	}
+
+	user, _ = auth.FindUserByID(adapter, &auth.UserModel{}, "1")
+	stored := user.GetHashedPassword()
+	if !strings.HasPrefix(stored, "$argon2id$") {
+		t.Fatalf("stored hash %s was not upgraded", stored)
+	}
+	if ok, _ := h.Verify(stored, "pw"); !ok {
+		t.Error("upgraded hash does not verify")
+	}
+
+	// A current hash is left alone.
+	if err := auth.RehashIfNeeded(adapter, h, user, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	user, _ = auth.FindUserByID(adapter, &auth.UserModel{}, "1")
+	if user.GetHashedPassword() != stored {
+		t.Error("a current hash was rehashed")
+	}
+}
@@ -0,0 +1,111 @@
+package hasher
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Upper bounds for the parameters of a stored hash: N = 2^20 with r = 8
+// already takes 1 GiB, and scrypt requires r*p < 2^30.
+const (
+	maxScryptLogN = 20
+	maxScryptRP   = 1<<30 - 1
+)
+
+// Scrypt hashes are encoded as "$scrypt$ln=<log2 N>,r=<r>,p=<p>$salt$hash".
+type Scrypt struct {
+	LogN       uint8 // N = 2^LogN
+	R          int
+	P          int
+	SaltLength int
+	KeyLength  int
+}
+
+// NewScrypt returns a scrypt hasher with N=2^17, r=8, p=1 as recommended
+// by OWASP.
+func NewScrypt() *Scrypt {
+	return &Scrypt{
+		LogN:       17,
+		R:          8,
+		P:          1,
+		SaltLength: 16,
+		KeyLength:  32,
+	}
+}
+
+func (s *Scrypt) Hash(password string) (string, error) {
+	salt, err := newSalt(s.SaltLength)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := scrypt.Key([]byte(password), salt, 1<<s.LogN, s.R, s.P, s.KeyLength)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d$%s$%s",
+		s.LogN, s.R, s.P, b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+}
+
+func (s *Scrypt) Verify(hash, password string) (bool, error) {
+	p, salt, key, err := s.decode(hash)
+	if err != nil {
+		return false, err
+	}
+
+	actual, err := scrypt.Key([]byte(password), salt, 1<<p.LogN, p.R, p.P, len(key))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(actual, key) == 1, nil
+}
+
+func (s *Scrypt) NeedsRehash(hash string) bool {
+	p, salt, key, err := s.decode(hash)
+	if err != nil {
+		return true
+	}
+
+	return p.LogN != s.LogN || p.R != s.R || p.P != s.P ||
+		len(salt) != s.SaltLength || len(key) != s.KeyLength
+}
+
+func (s *Scrypt) Identifies(hash string) bool {
+	return strings.HasPrefix(hash, "$scrypt$")
+}
+
+func (s *Scrypt) decode(hash string) (*Scrypt, []byte, []byte, error) {
+	params, salt, key, err := parsePHC(hash, "scrypt")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	ln, err := paramInt(params, "ln")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	r, err := paramInt(params, "r")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	p, err := paramInt(params, "p")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// A stored hash decides how much memory and time Verify spends, so
+	// parameters beyond what any sane configuration uses are rejected
+	// instead of computed.
+	if ln < 1 || ln > maxScryptLogN || r < 1 || p < 1 || r > maxScryptRP/p {
+		return nil, nil, nil, ErrMalformedHash
+	}
+	if len(salt) == 0 || len(key) < 16 {
+		return nil, nil, nil, ErrMalformedHash
+	}
+
+	return &Scrypt{LogN: uint8(ln), R: r, P: p}, salt, key, nil
+}
@@ -2,8 +2,71 @@ package auth
 
 import "github.com/Lumicrate/gompose/http"
 
+const (
+	CtxUserID        = "user_id"
+	CtxAuthProvider  = "auth_provider"
+	CtxAuthProviders = "auth_providers"
+)
+
+// AuthProvider is initialized by core.App before routes are registered and
+// closed on shutdown.
 type AuthProvider interface {
 	Init() error
 	RegisterRoutes(engine http.HTTPEngine)
 	Middleware() http.MiddlewareFunc
+	Close() error
+}
+
+// Authenticator is implemented by providers that can check a request
+// without writing a response, which is what allows them to be chained.
+// Authenticate returns the ID of the authenticated subject.
+type Authenticator interface {
+	Name() string
+	Authenticate(ctx http.Context) (string, error)
+}
+
+// SecurityScheme describes how a provider expects credentials to be sent,
+// in the terms used by OpenAPI security schemes.
+type SecurityScheme struct {
+	Name         string // key under components.securitySchemes
+	Type         string // "http" or "apiKey"
+	Scheme       string // for Type "http", e.g. "bearer"
+	BearerFormat string
+	In           string // for Type "apiKey": "header", "query" or "cookie"
+	ParamName    string // for Type "apiKey": header, query or cookie name
+}
+
+// SecurityDescriber is implemented by providers that can document
+// themselves in Swagger. SecurityRequirements returns the alternatives a
+// client may use; every scheme inside one alternative must be satisfied.
+type SecurityDescriber interface {
+	SecuritySchemes() []SecurityScheme
+	SecurityRequirements() [][]string
+}
+
+// Credentials is the body of a password login. Code, a TOTP code, or
+// RecoveryCode is needed for users with two-factor authentication when the
+// login has to finish in one request.
+type Credentials struct {
+	Email        string `json:"email"`
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// LoginChecker checks credentials with all of a provider's login rules and
+// returns the user. When it refuses the login it has already answered the
+// request. JWTAuthProvider implements it, so that other providers, e.g.
+// SessionAuthProvider, log users in under the same rules.
+type LoginChecker interface {
+	CheckLogin(ctx http.Context, creds Credentials) (AuthUser, bool)
+}
+
+// PasswordHasher hashes and verifies passwords. NeedsRehash reports whether
+// a stored hash was made with other algorithms or weaker parameters than the
+// hasher currently uses, so it can be replaced after a successful login.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(hash, password string) (bool, error)
+	NeedsRehash(hash string) bool
 }
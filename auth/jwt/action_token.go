@@ -0,0 +1,113 @@
+package jwt
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Lumicrate/gompose/db"
+	"github.com/Lumicrate/gompose/utils"
+)
+
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeResetPassword = "reset_password"
+)
+
+var errInvalidActionToken = errors.New("invalid or expired token")
+
+// ActionToken is a single-use token sent to a user by email. Only a hash of
+// the token is stored, so a leaked table cannot be used to take over accounts.
+type ActionToken struct {
+	ID        string     `gorm:"primaryKey" json:"id" bson:"id"`
+	UserID    string     `gorm:"index" json:"user_id" bson:"user_id"`
+	Purpose   string     `json:"purpose" bson:"purpose"`
+	TokenHash string     `gorm:"size:64;uniqueIndex" json:"token_hash" bson:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" bson:"expires_at"`
+	UsedAt    *time.Time `json:"used_at" bson:"used_at"`
+}
+
+// issueActionToken stores a new token for the user and returns the value to
+// send. The value is a random part followed by its HMAC, so forged tokens
+// are rejected before the database is queried.
+func (j *JWTAuthProvider) issueActionToken(userID, purpose string, ttl time.Duration) (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	raw := base64.RawURLEncoding.EncodeToString(random)
+	token := raw + "." + j.signActionToken(purpose, raw)
+
+	record := &ActionToken{
+		ID:        utils.GenerateUUID(),
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashActionToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	if err := j.DB.Create(record); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// consumeActionToken checks a token for the given purpose, marks it as used
+// and returns the ID of the user it was issued to.
+func (j *JWTAuthProvider) consumeActionToken(token, purpose string) (string, error) {
+	raw, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(j.signActionToken(purpose, raw))) {
+		return "", errInvalidActionToken
+	}
+
+	found, err := j.DB.FindAll(&ActionToken{}, map[string]any{
+		"token_hash": hashActionToken(token),
+	}, db.Pagination{Limit: 1}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	records := reflect.ValueOf(found)
+	if records.Len() == 0 {
+		return "", errInvalidActionToken
+	}
+
+	record := records.Index(0).Interface().(ActionToken)
+	if record.Purpose != purpose || record.UsedAt != nil || time.Now().After(record.ExpiresAt) {
+		return "", errInvalidActionToken
+	}
+
+	// Claim the token only while it is unused, so that concurrent requests
+	// with the same token cannot both pass the check above.
+	claimed, err := j.DB.UpdateWhere(&ActionToken{},
+		map[string]any{"id": record.ID, "used_at": nil},
+		map[string]any{"used_at": time.Now()},
+	)
+	if err != nil {
+		return "", err
+	}
+	if claimed == 0 {
+		return "", errInvalidActionToken
+	}
+
+	return record.UserID, nil
+}
+
+func (j *JWTAuthProvider) signActionToken(purpose, raw string) string {
+	mac := hmac.New(sha256.New, []byte(j.SecretKey))
+	mac.Write([]byte(purpose + ":" + raw))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func hashActionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -3,10 +3,18 @@ package jwt
 import (
 	"fmt"
 	"github.com/Lumicrate/gompose/auth"
+	"github.com/Lumicrate/gompose/auth/hasher"
+	"github.com/Lumicrate/gompose/auth/totp"
 	"github.com/Lumicrate/gompose/db"
 	"github.com/Lumicrate/gompose/http"
 	"github.com/Lumicrate/gompose/utils"
+	"golang.org/x/crypto/bcrypt"
+	"io"
+	"log/slog"
+	"math"
 	"reflect"
+	"strconv"
+	"sync"
 	"time"
 )
 
@@ -15,14 +23,49 @@ type JWTAuthProvider struct {
 	UserModel any // optional: developer can override
 	DB        db.DBAdapter
 	TokenTTL  time.Duration
+
+	ProfileFields []string // user model fields that PATCH /auth/me may change
+
+	Hasher         auth.PasswordHasher
+	PasswordPolicy *auth.PasswordPolicy // nil accepts any password
+	LoginGuard     *auth.LoginGuard     // nil disables brute-force protection
+
+	Mailer               auth.Mailer // optional: enables verification and reset routes
+	RequireVerifiedEmail bool
+	VerificationURL      string
+	ResetURL             string
+	VerificationTTL      time.Duration
+	ResetTTL             time.Duration
+
+	TwoFactorIssuer string // optional: enables TOTP two-factor routes
+	TOTP            totp.Config
+	ChallengeTTL    time.Duration
+
+	Logger *slog.Logger // nil uses slog.Default()
+
+	// dummyHash is compared against when the email is unknown, so that a
+	// login takes the same time whether or not the account exists.
+	dummyHash string
+
+	// resets tracks password reset emails still being sent, so that Close
+	// waits for them before closing the Mailer.
+	resets sync.WaitGroup
 }
 
 func NewJWTAuthProvider(secretKey string, dbAdapter db.DBAdapter) *JWTAuthProvider {
 	return &JWTAuthProvider{
-		SecretKey: secretKey,
-		DB:        dbAdapter,
-		UserModel: auth.UserModel{},
-		TokenTTL:  time.Hour * 72, // default is 3 days
+		SecretKey:       secretKey,
+		DB:              dbAdapter,
+		UserModel:       auth.UserModel{},
+		TokenTTL:        time.Hour * 72, // default is 3 days
+		ProfileFields:   []string{"Email"},
+		VerificationTTL: time.Hour * 48,
+		ResetTTL:        time.Hour,
+		TOTP:            totp.DefaultConfig(),
+		ChallengeTTL:    time.Minute * 5,
+		Hasher:          hasher.NewBcrypt(bcrypt.DefaultCost),
+		PasswordPolicy:  auth.DefaultPasswordPolicy(),
+		LoginGuard:      auth.NewLoginGuard(),
 	}
 }
 
@@ -35,16 +78,75 @@ func (j *JWTAuthProvider) Init() error {
 		return fmt.Errorf("jwt: UserModel must be provided via SetUserModel")
 	}
 
+	if err := j.checkProfileFields(); err != nil {
+		return err
+	}
+
+	if j.RequireVerifiedEmail {
+		if j.Mailer == nil {
+			return fmt.Errorf("jwt: a Mailer must be provided via SetMailer when email verification is required")
+		}
+		if _, ok := j.newUser().(auth.VerifiableUser); !ok {
+			return fmt.Errorf("jwt: UserModel must implement VerifiableUser when email verification is required")
+		}
+	}
+
+	if j.Hasher == nil {
+		return fmt.Errorf("jwt: a PasswordHasher must be provided via SetPasswordHasher")
+	}
+
+	dummyHash, err := j.Hasher.Hash(utils.GenerateUUID())
+	if err != nil {
+		return fmt.Errorf("jwt: %w", err)
+	}
+	j.dummyHash = dummyHash
+
 	if err := j.DB.Migrate([]any{j.UserModel}); err != nil {
 		return fmt.Errorf("jwt: failed to migrate user model: %w", err)
 	}
 
+	if j.Mailer != nil {
+		if err := j.DB.Migrate([]any{&ActionToken{}}); err != nil {
+			return fmt.Errorf("jwt: failed to migrate action tokens: %w", err)
+		}
+	}
+
+	if j.twoFactorEnabled() {
+		if err := j.TOTP.Check(); err != nil {
+			return fmt.Errorf("jwt: %w", err)
+		}
+		if err := j.DB.Migrate([]any{&TwoFactor{}}); err != nil {
+			return fmt.Errorf("jwt: failed to migrate two-factor settings: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Close waits for pending reset emails and closes the Mailer if it holds
+// resources.
+func (j *JWTAuthProvider) Close() error {
+	j.resets.Wait()
+	if c, ok := j.Mailer.(io.Closer); ok {
+		return c.Close()
+	}
 	return nil
 }
 
 func (j *JWTAuthProvider) RegisterRoutes(engine http.HTTPEngine) {
 	engine.RegisterRoute("POST", "/auth/register", j.registerHandler, j.UserModel, false)
 	engine.RegisterRoute("POST", "/auth/login", j.loginHandler, j.UserModel, false)
+	j.registerProfileRoutes(engine)
+
+	if j.Mailer != nil {
+		engine.RegisterRoute("POST", "/auth/verify", j.verifyHandler, nil, false)
+		engine.RegisterRoute("POST", "/auth/forgot-password", j.forgotPasswordHandler, nil, false)
+		engine.RegisterRoute("POST", "/auth/reset-password", j.resetPasswordHandler, nil, false)
+	}
+
+	if j.twoFactorEnabled() {
+		j.registerTwoFactorRoutes(engine)
+	}
 }
 
 func (j *JWTAuthProvider) SetUserModel(model any) *JWTAuthProvider {
@@ -60,12 +162,59 @@ func (j *JWTAuthProvider) SetTokenTTL(t time.Duration) *JWTAuthProvider {
 	return j
 }
 
-func (j *JWTAuthProvider) registerHandler(ctx http.Context) {
-	t := reflect.TypeOf(j.UserModel)
-	if t.Kind() == reflect.Ptr {
-		t = t.Elem()
+// SetPasswordPolicy replaces the default policy (at least 8 characters).
+// Passing nil accepts any password.
+func (j *JWTAuthProvider) SetPasswordPolicy(policy *auth.PasswordPolicy) *JWTAuthProvider {
+	j.PasswordPolicy = policy
+	return j
+}
+
+// SetLoginGuard replaces the default brute-force protection. Passing nil
+// disables it.
+func (j *JWTAuthProvider) SetLoginGuard(guard *auth.LoginGuard) *JWTAuthProvider {
+	j.LoginGuard = guard
+	return j
+}
+
+// SetPasswordHasher replaces the default bcrypt hasher. To keep existing
+// hashes working while moving to another algorithm, wrap it with
+// hasher.Upgrade(newHasher, hasher.NewBcrypt(0)).
+func (j *JWTAuthProvider) SetPasswordHasher(h auth.PasswordHasher) *JWTAuthProvider {
+	j.Hasher = h
+	return j
+}
+
+// rehashIfNeeded upgrades an outdated hash after a successful login.
+// Failures are only logged since the login itself succeeded.
+func (j *JWTAuthProvider) rehashIfNeeded(user auth.AuthUser, password string) {
+	if err := auth.RehashIfNeeded(j.DB, j.Hasher, user, password); err != nil {
+		j.logger().Error("jwt: "+err.Error(), "user_id", user.GetID())
 	}
-	newUser := reflect.New(t).Interface()
+}
+
+// SetLogger sets the logger for errors that do not fail the request, such
+// as a failed password rehash. The default is slog.Default().
+func (j *JWTAuthProvider) SetLogger(logger *slog.Logger) *JWTAuthProvider {
+	j.Logger = logger
+	return j
+}
+
+func (j *JWTAuthProvider) logger() *slog.Logger {
+	if j.Logger == nil {
+		return slog.Default()
+	}
+	return j.Logger
+}
+
+func (j *JWTAuthProvider) validatePassword(password string) error {
+	if j.PasswordPolicy == nil {
+		return nil
+	}
+	return j.PasswordPolicy.Validate(password)
+}
+
+func (j *JWTAuthProvider) registerHandler(ctx http.Context) {
+	newUser := j.newUser()
 
 	if err := ctx.Bind(newUser); err != nil {
 		ctx.JSON(400, map[string]string{"error": "invalid input: " + err.Error()})
@@ -79,12 +228,20 @@ func (j *JWTAuthProvider) registerHandler(ctx http.Context) {
 	}
 
 	password := authUser.GetHashedPassword()
-	hashed, err := utils.GenerateFromPassword(password)
+	if err := j.validatePassword(password); err != nil {
+		ctx.JSON(400, map[string]string{"error": err.Error()})
+		return
+	}
+
+	hashed, err := j.Hasher.Hash(password)
 	if err != nil {
 		ctx.JSON(400, map[string]string{"error": "invalid input: " + err.Error()})
+		return
 	}
 
 	reflect.ValueOf(newUser).Elem().FieldByName("Password").SetString(hashed)
+	// Only a verification link may mark the address as verified.
+	auth.SetUserField(newUser, "EmailVerified", false)
 	idField := reflect.ValueOf(newUser).Elem().FieldByName("ID")
 	if idField.IsValid() && idField.CanSet() {
 		switch idField.Kind() {
@@ -98,6 +255,13 @@ func (j *JWTAuthProvider) registerHandler(ctx http.Context) {
 		return
 	}
 
+	if j.Mailer != nil {
+		if err := j.sendVerificationEmail(ctx, authUser); err != nil {
+			ctx.JSON(500, map[string]string{"error": "user registered but the verification email could not be sent"})
+			return
+		}
+	}
+
 	ctx.JSON(201, map[string]string{"message": "user registered successfully"})
 }
 
@@ -112,74 +276,165 @@ func (j *JWTAuthProvider) loginHandler(ctx http.Context) {
 		return
 	}
 
-	t := reflect.TypeOf(j.UserModel)
-	if t.Kind() == reflect.Ptr {
-		t = t.Elem()
+	authUser, ok := j.checkPassword(ctx, payload.Email, payload.Password)
+	if !ok {
+		return
 	}
-	user := reflect.New(t).Interface()
-
-	foundUsers, err := j.DB.FindAll(user, map[string]any{
-		"email": payload.Email,
-	}, db.Pagination{Limit: 1}, nil)
 
+	// With two-factor authentication the password only earns a challenge
+	// token, which /auth/2fa/verify exchanges for an access token.
+	challenge, err := j.twoFactorChallenge(authUser.GetID())
 	if err != nil {
-		ctx.JSON(500, map[string]string{"error": "failed to query user"})
+		ctx.JSON(500, map[string]string{"error": "failed to generate token: " + err.Error()})
+		return
+	}
+	if challenge != "" {
+		ctx.JSON(200, map[string]any{"two_factor_required": true, "challenge_token": challenge})
 		return
 	}
 
-	usersVal := reflect.ValueOf(foundUsers)
-	if usersVal.Len() == 0 {
-		ctx.JSON(401, map[string]string{"error": "invalid username or password"})
+	token, err := utils.GenerateJWT(authUser.GetID(), j.SecretKey, j.TokenTTL)
+	if err != nil {
+		ctx.JSON(500, map[string]string{"error": "failed to generate token: " + err.Error()})
 		return
 	}
 
-	userVal := usersVal.Index(0)
-	var authUser auth.AuthUser
-	var ok bool
+	ctx.JSON(200, map[string]string{"token": token})
+}
 
-	if userVal.Kind() == reflect.Ptr {
-		authUser, ok = userVal.Interface().(auth.AuthUser)
-	} else {
-		authUser, ok = userVal.Addr().Interface().(auth.AuthUser)
+// CheckLogin applies the rules of POST /auth/login for another provider:
+// the login guard, the password, rehashing and email verification, and for
+// users with two-factor authentication a TOTP or recovery code in the same
+// request, since there is no challenge token to exchange.
+func (j *JWTAuthProvider) CheckLogin(ctx http.Context, creds auth.Credentials) (auth.AuthUser, bool) {
+	authUser, ok := j.checkPassword(ctx, creds.Email, creds.Password)
+	if !ok || !j.twoFactorEnabled() {
+		return authUser, ok
 	}
 
-	if !ok {
-		ctx.JSON(500, map[string]string{"error": "user model must implement AuthUser"})
-		return
+	record, err := j.findTwoFactor(authUser.GetID())
+	if err != nil {
+		ctx.JSON(500, map[string]string{"error": "failed to load two-factor settings"})
+		return nil, false
+	}
+	if record == nil || !record.Enabled {
+		return authUser, true
+	}
+
+	if creds.Code == "" && creds.RecoveryCode == "" {
+		ctx.JSON(401, map[string]any{"error": "a two-factor code is required", "two_factor_required": true})
+		return nil, false
+	}
+	if !j.verifySecondFactor(ctx, record, creds.Code, creds.RecoveryCode) {
+		return nil, false
+	}
+	return authUser, true
+}
+
+// checkPassword runs the checks every login shares: the login guard, the
+// password, rehashing and email verification. It answers the request
+// itself when the login is refused.
+func (j *JWTAuthProvider) checkPassword(ctx http.Context, email, password string) (auth.AuthUser, bool) {
+	ip := ctx.RemoteIP()
+	if j.LoginGuard != nil {
+		if wait := j.LoginGuard.Check(email, ip); wait > 0 {
+			ctx.SetHeader("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			ctx.JSON(429, map[string]string{"error": "too many failed login attempts, try again later"})
+			return nil, false
+		}
+		sleep(ctx, j.LoginGuard.Penalty(email))
+	}
+
+	authUser, err := j.findUserByEmail(email)
+	if err != nil {
+		ctx.JSON(500, map[string]string{"error": "failed to query user"})
+		return nil, false
+	}
+
+	// Always compare a hash, even for unknown emails, so that response times
+	// do not reveal which accounts exist.
+	hashed := j.dummyHash
+	if authUser != nil {
+		hashed = authUser.GetHashedPassword()
 	}
 
-	if err := utils.CompareHashAndPassword(authUser.GetHashedPassword(), payload.Password); err != nil {
+	if ok, _ := j.Hasher.Verify(hashed, password); !ok || authUser == nil {
+		if j.LoginGuard != nil {
+			j.LoginGuard.Fail(email, ip)
+		}
 		ctx.JSON(401, map[string]string{"error": "invalid username or password"})
+		return nil, false
+	}
+
+	if j.LoginGuard != nil {
+		j.LoginGuard.Succeed(email)
+	}
+
+	j.rehashIfNeeded(authUser, password)
+
+	if j.RequireVerifiedEmail {
+		if verifiable, ok := authUser.(auth.VerifiableUser); ok && !verifiable.IsEmailVerified() {
+			ctx.JSON(403, map[string]string{"error": "email address is not verified"})
+			return nil, false
+		}
+	}
+
+	return authUser, true
+}
+
+// sleep waits for d unless the request is cancelled first.
+func sleep(ctx http.Context, d time.Duration) {
+	if d <= 0 {
 		return
 	}
 
-	token, err := utils.GenerateJWT(authUser.GetID(), j.SecretKey, j.TokenTTL)
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Request().Context().Done():
+	}
+}
+
+func (j *JWTAuthProvider) Name() string {
+	return "jwt"
+}
+
+func (j *JWTAuthProvider) Authenticate(ctx http.Context) (string, error) {
+	tokenStr, err := utils.ExtractBearerToken(ctx.Header("Authorization"))
 	if err != nil {
-		ctx.JSON(500, map[string]string{"error": "failed to generate token: " + err.Error()})
+		return "", err
 	}
 
-	ctx.JSON(200, map[string]string{"token": token})
+	claims, err := utils.ValidateJWT(tokenStr, j.SecretKey)
+	if err != nil {
+		return "", err
+	}
+
+	// Access tokens carry no type; anything else, such as a two-factor
+	// challenge, must not grant access.
+	if _, typed := claims[tokenTypeClaim]; typed {
+		return "", fmt.Errorf("invalid token type")
+	}
+
+	subject, _ := claims["sub"].(string)
+	return subject, nil
 }
 
 func (j *JWTAuthProvider) Middleware() http.MiddlewareFunc {
-	return func(next http.HandlerFunc) http.HandlerFunc {
-		return func(ctx http.Context) {
-			tokenStr, err := utils.ExtractBearerToken(ctx.Header("Authorization"))
-			if err != nil {
-				ctx.JSON(401, map[string]string{"error": err.Error()})
-				ctx.Abort()
-				return
-			}
-
-			claims, err := utils.ValidateJWT(tokenStr, j.SecretKey)
-			if err != nil {
-				ctx.JSON(401, map[string]string{"error": err.Error()})
-				ctx.Abort()
-				return
-			}
-
-			ctx.Set("user_id", claims["sub"])
-			next(ctx)
-		}
-	}
+	return auth.Middleware(j)
+}
+
+func (j *JWTAuthProvider) SecuritySchemes() []auth.SecurityScheme {
+	return []auth.SecurityScheme{{
+		Name:         "BearerAuth",
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+	}}
+}
+
+func (j *JWTAuthProvider) SecurityRequirements() [][]string {
+	return [][]string{{"BearerAuth"}}
 }
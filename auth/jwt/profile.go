@@ -0,0 +1,267 @@
+package jwt
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Lumicrate/gompose/auth"
+	"github.com/Lumicrate/gompose/http"
+)
+
+// readOnlyUserFields can never be listed in ProfileFields: they are
+// changed only by their own routes.
+var readOnlyUserFields = []string{"ID", "Password", "EmailVerified"}
+
+// SetProfileFields sets the user model fields, by Go name, that a user may
+// change through PATCH /auth/me. The default is only "Email".
+func (j *JWTAuthProvider) SetProfileFields(fields ...string) *JWTAuthProvider {
+	j.ProfileFields = fields
+	return j
+}
+
+func (j *JWTAuthProvider) checkProfileFields() error {
+	for _, field := range j.ProfileFields {
+		for _, readOnly := range readOnlyUserFields {
+			if field == readOnly {
+				return fmt.Errorf("jwt: profile field %s is read-only", field)
+			}
+		}
+		if jsonFieldName(j.UserModel, field) == "" {
+			return fmt.Errorf("jwt: UserModel has no serialized field %s for ProfileFields", field)
+		}
+	}
+	return nil
+}
+
+func (j *JWTAuthProvider) registerProfileRoutes(engine http.HTTPEngine) {
+	protected := j.Middleware()
+
+	// The user model is not the entity here: the API docs would list the
+	// password hash, and GET would get a query filter for every field.
+	engine.RegisterRoute("GET", "/auth/me", protected(j.getProfileHandler), nil, true)
+	engine.RegisterRoute("PATCH", "/auth/me", protected(j.patchProfileHandler), j.profileEntity(), true)
+	engine.RegisterRoute("POST", "/auth/change-password", protected(j.changePasswordHandler), nil, true)
+}
+
+// profileEntity returns a value of a struct type with only the
+// ProfileFields of the user model, for the docs of PATCH /auth/me.
+func (j *JWTAuthProvider) profileEntity() any {
+	t := reflect.TypeOf(j.UserModel)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var fields []reflect.StructField
+	seen := map[string]bool{}
+	for _, name := range j.ProfileFields {
+		f, ok := t.FieldByName(name)
+		if !ok || !f.IsExported() || seen[f.Name] {
+			continue
+		}
+		seen[f.Name] = true
+		fields = append(fields, reflect.StructField{Name: f.Name, Type: f.Type, Tag: f.Tag})
+	}
+	return reflect.New(reflect.StructOf(fields)).Interface()
+}
+
+func (j *JWTAuthProvider) currentUser(ctx http.Context) (auth.AuthUser, bool) {
+	userID, _ := ctx.Get(auth.CtxUserID).(string)
+
+	user, err := j.findUserByID(userID)
+	if err != nil {
+		ctx.JSON(404, map[string]string{"error": "user not found"})
+		return nil, false
+	}
+	return user, true
+}
+
+func (j *JWTAuthProvider) getProfileHandler(ctx http.Context) {
+	user, ok := j.currentUser(ctx)
+	if !ok {
+		return
+	}
+
+	profile, err := sanitizeUser(user)
+	if err != nil {
+		ctx.JSON(500, map[string]string{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, profile)
+}
+
+func (j *JWTAuthProvider) patchProfileHandler(ctx http.Context) {
+	user, ok := j.currentUser(ctx)
+	if !ok {
+		return
+	}
+
+	patchData := map[string]any{}
+	if err := ctx.BindJSON(&patchData); err != nil {
+		ctx.JSON(400, map[string]string{"error": "invalid patch data"})
+		return
+	}
+
+	// Only the configured fields may change. Keys are matched
+	// case-insensitively, as encoding/json does, and passed on under the
+	// field's exact JSON name.
+	allowed := map[string]any{}
+	for key, value := range patchData {
+		name := ""
+		for _, field := range j.ProfileFields {
+			if jsonName := jsonFieldName(user, field); strings.EqualFold(key, jsonName) {
+				name = jsonName
+				break
+			}
+		}
+		if name == "" {
+			ctx.JSON(400, map[string]string{"error": "field " + key + " cannot be changed here"})
+			return
+		}
+		allowed[name] = value
+	}
+
+	previousEmail := user.GetEmail()
+
+	patchBytes, _ := json.Marshal(allowed)
+	if err := json.Unmarshal(patchBytes, user); err != nil {
+		ctx.JSON(400, map[string]string{"error": "invalid patch data: " + err.Error()})
+		return
+	}
+
+	// A new address has to be verified again.
+	emailChanged := !strings.EqualFold(previousEmail, user.GetEmail())
+	if emailChanged {
+		auth.SetUserField(user, "EmailVerified", false)
+	}
+
+	if err := j.DB.Update(user); err != nil {
+		ctx.JSON(500, map[string]string{"error": "failed to update user: " + err.Error()})
+		return
+	}
+
+	if emailChanged && j.Mailer != nil {
+		if err := j.sendVerificationEmail(ctx, user); err != nil {
+			ctx.JSON(500, map[string]string{"error": "profile updated but the verification email could not be sent"})
+			return
+		}
+	}
+
+	profile, err := sanitizeUser(user)
+	if err != nil {
+		ctx.JSON(500, map[string]string{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(200, profile)
+}
+
+// changePasswordHandler replaces the password of the current user. Tokens
+// are stateless and are not revoked: those issued before stay valid until
+// they expire, and so do sessions of the session provider.
+func (j *JWTAuthProvider) changePasswordHandler(ctx http.Context) {
+	payload := struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}{}
+
+	if err := ctx.BindJSON(&payload); err != nil {
+		ctx.JSON(400, map[string]string{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	user, ok := j.currentUser(ctx)
+	if !ok {
+		return
+	}
+
+	// A stolen token must not give unlimited guesses at the current
+	// password, so attempts are throttled like logins.
+	guardKey := "pw:" + user.GetID()
+	ip := ctx.RemoteIP()
+	if j.LoginGuard != nil {
+		if wait := j.LoginGuard.Check(guardKey, ip); wait > 0 {
+			ctx.SetHeader("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			ctx.JSON(429, map[string]string{"error": "too many failed attempts, try again later"})
+			return
+		}
+		sleep(ctx, j.LoginGuard.Penalty(guardKey))
+	}
+
+	if ok, _ := j.Hasher.Verify(user.GetHashedPassword(), payload.CurrentPassword); !ok {
+		if j.LoginGuard != nil {
+			j.LoginGuard.Fail(guardKey, ip)
+		}
+		ctx.JSON(401, map[string]string{"error": "current password is incorrect"})
+		return
+	}
+
+	if j.LoginGuard != nil {
+		j.LoginGuard.Succeed(guardKey)
+	}
+
+	if err := j.validatePassword(payload.NewPassword); err != nil {
+		ctx.JSON(400, map[string]string{"error": err.Error()})
+		return
+	}
+
+	hashed, err := j.Hasher.Hash(payload.NewPassword)
+	if err != nil {
+		ctx.JSON(400, map[string]string{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	auth.SetUserField(user, "Password", hashed)
+	if err := j.DB.Update(user); err != nil {
+		ctx.JSON(500, map[string]string{"error": "failed to change password: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(200, map[string]string{"message": "password changed successfully"})
+}
+
+// sanitizeUser converts a user to its JSON representation without the
+// password hash.
+func sanitizeUser(user any) (map[string]any, error) {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := map[string]any{}
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, err
+	}
+
+	if key := jsonFieldName(user, "Password"); key != "" {
+		delete(profile, key)
+	}
+	return profile, nil
+}
+
+// jsonFieldName returns the JSON key of a struct field, or an empty string
+// when the field does not exist or is not serialized.
+func jsonFieldName(model any, field string) string {
+	t := reflect.TypeOf(model)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	f, ok := t.FieldByName(field)
+	if !ok {
+		return ""
+	}
+
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	}
+	return name
+}
@@ -0,0 +1,448 @@
+package jwt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lumicrate/gompose/auth"
+	"github.com/Lumicrate/gompose/auth/totp"
+	"github.com/Lumicrate/gompose/db"
+	"github.com/Lumicrate/gompose/http"
+	"github.com/Lumicrate/gompose/utils"
+)
+
+const (
+	tokenTypeClaim      = "typ"
+	tokenTypeChallenge  = "2fa_challenge"
+	challengeStateClaim = "st"
+	recoveryCodeCount   = 10
+)
+
+// TwoFactor holds the TOTP enrollment of one user. ID is the user's ID.
+// The secret is encrypted with a key derived from the provider's SecretKey.
+type TwoFactor struct {
+	ID            string `gorm:"primaryKey" json:"id" bson:"id"`
+	Secret        string `json:"secret" bson:"secret"`
+	Enabled       bool   `json:"enabled" bson:"enabled"`
+	RecoveryCodes string `json:"recovery_codes" bson:"recovery_codes"` // comma-separated hashes
+	LastUsedStep  int64  `json:"last_used_step" bson:"last_used_step"`
+}
+
+// EnableTwoFactor registers the TOTP enrollment routes and makes login
+// return a challenge token for users who have confirmed an enrollment.
+// The issuer is the name shown in authenticator apps.
+func (j *JWTAuthProvider) EnableTwoFactor(issuer string) *JWTAuthProvider {
+	j.TwoFactorIssuer = issuer
+	return j
+}
+
+func (j *JWTAuthProvider) twoFactorEnabled() bool {
+	return j.TwoFactorIssuer != ""
+}
+
+func (j *JWTAuthProvider) registerTwoFactorRoutes(engine http.HTTPEngine) {
+	protected := j.Middleware()
+
+	engine.RegisterRoute("POST", "/auth/2fa/enroll", protected(j.enrollTwoFactorHandler), nil, true)
+	engine.RegisterRoute("POST", "/auth/2fa/confirm", protected(j.confirmTwoFactorHandler), nil, true)
+	engine.RegisterRoute("POST", "/auth/2fa/disable", protected(j.disableTwoFactorHandler), nil, true)
+	engine.RegisterRoute("POST", "/auth/2fa/verify", j.verifyTwoFactorHandler, nil, false)
+}
+
+// enrollTwoFactorHandler creates a new, not yet enabled, secret for the
+// current user. It has to be confirmed with a valid code before it is used.
+func (j *JWTAuthProvider) enrollTwoFactorHandler(ctx http.Context) {
+	userID, _ := ctx.Get(auth.CtxUserID).(string)
+
+	user, err := j.findUserByID(userID)
+	if err != nil {
+		ctx.JSON(404, map[string]string{"error": "user not found"})
+		return
+	}
+
+	existing, err := j.findTwoFactor(userID)
+	if err != nil {
+		ctx.JSON(500, map[string]string{"error": "failed to query two-factor settings"})
+		return
+	}
+	if existing != nil && existing.Enabled {
+		ctx.JSON(409, map[string]string{"error": "two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		ctx.JSON(500, map[string]string{"error": "failed to generate secret"})
+		return
+	}
+
+	encrypted, err := j.encryptSecret(secret)
+	if err != nil {
+		ctx.JSON(500, map[string]string{"error": "failed to store secret"})
+		return
+	}
+
+	record := &TwoFactor{ID: userID, Secret: encrypted}
+	if existing == nil {
+		err = j.DB.Create(record)
+	} else {
+		err = j.DB.Update(record)
+	}
+	if err != nil {
+		ctx.JSON(500, map[string]string{"error": "failed to store secret: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(200, map[string]string{
+		"secret":      secret,
+		"otpauth_uri": j.TOTP.URI(secret, j.TwoFactorIssuer, user.GetEmail()),
+	})
+}
+
+// confirmTwoFactorHandler enables the pending enrollment once the user
+// proves the authenticator app works, and returns the recovery codes.
+// They are shown only once.
+func (j *JWTAuthProvider) confirmTwoFactorHandler(ctx http.Context) {
+	payload := struct {
+		Code string `json:"code"`
+	}{}
+
+	if err := ctx.BindJSON(&payload); err != nil {
+		ctx.JSON(400, map[string]string{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	userID, _ := ctx.Get(auth.CtxUserID).(string)
+	record, err := j.findTwoFactor(userID)
+	if err != nil {
+		ctx.JSON(500, map[string]string{"error": "failed to query two-factor settings"})
+		return
+	}
+	if record == nil || record.Enabled {
+		ctx.JSON(409, map[string]string{"error": "no pending two-factor enrollment"})
+		return
+	}
+
+	old := *record
+	if !j.checkTOTP(record, payload.Code) {
+		ctx.JSON(400, map[string]string{"error": "invalid code"})
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		ctx.JSON(500, map[string]string{"error": "failed to generate recovery codes"})
+		return
+	}
+
+	saved, err := j.saveSecondFactor(old, map[string]any{
+		"enabled":        true,
+		"recovery_codes": strings.Join(hashes, ","),
+		"last_used_step": record.LastUsedStep,
+	})
+	if err != nil {
+		ctx.JSON(500, map[string]string{"error": "failed to enable two-factor authentication: " + err.Error()})
+		return
+	}
+	if !saved {
+		ctx.JSON(400, map[string]string{"error": "invalid code"})
+		return
+	}
+
+	ctx.JSON(200, map[string]any{"recovery_codes": codes})
+}
+
+func (j *JWTAuthProvider) disableTwoFactorHandler(ctx http.Context) {
+	payload := struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}{}
+
+	if err := ctx.BindJSON(&payload); err != nil {
+		ctx.JSON(400, map[string]string{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	userID, _ := ctx.Get(auth.CtxUserID).(string)
+	record, err := j.findTwoFactor(userID)
+	if err != nil {
+		ctx.JSON(500, map[string]string{"error": "failed to query two-factor settings"})
+		return
+	}
+	if record == nil || !record.Enabled {
+		ctx.JSON(409, map[string]string{"error": "two-factor authentication is not enabled"})
+		return
+	}
+
+	old := *record
+	if !j.checkSecondFactor(record, payload.Code, payload.RecoveryCode) {
+		ctx.JSON(400, map[string]string{"error": "invalid code"})
+		return
+	}
+
+	saved, err := j.saveSecondFactor(old, map[string]any{
+		"secret":         "",
+		"enabled":        false,
+		"recovery_codes": "",
+		"last_used_step": 0,
+	})
+	if err != nil {
+		ctx.JSON(500, map[string]string{"error": "failed to disable two-factor authentication: " + err.Error()})
+		return
+	}
+	if !saved {
+		ctx.JSON(400, map[string]string{"error": "invalid code"})
+		return
+	}
+
+	ctx.JSON(200, map[string]string{"message": "two-factor authentication disabled"})
+}
+
+// verifyTwoFactorHandler exchanges the challenge token returned by login and
+// a TOTP or recovery code for an access token.
+func (j *JWTAuthProvider) verifyTwoFactorHandler(ctx http.Context) {
+	payload := struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+		RecoveryCode   string `json:"recovery_code"`
+	}{}
+
+	if err := ctx.BindJSON(&payload); err != nil {
+		ctx.JSON(400, map[string]string{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	claims, err := utils.ValidateJWT(payload.ChallengeToken, j.SecretKey)
+	if err != nil || claims[tokenTypeClaim] != tokenTypeChallenge {
+		ctx.JSON(401, map[string]string{"error": "invalid or expired challenge token"})
+		return
+	}
+	userID, _ := claims["sub"].(string)
+
+	// Every accepted code changes the record's state, so a challenge token
+	// bound to the state it was issued for works only once.
+	record, err := j.findTwoFactor(userID)
+	if err != nil || record == nil || !record.Enabled || claims[challengeStateClaim] != challengeState(record) {
+		ctx.JSON(401, map[string]string{"error": "invalid or expired challenge token"})
+		return
+	}
+
+	if !j.verifySecondFactor(ctx, record, payload.Code, payload.RecoveryCode) {
+		return
+	}
+
+	token, err := utils.GenerateJWT(userID, j.SecretKey, j.TokenTTL)
+	if err != nil {
+		ctx.JSON(500, map[string]string{"error": "failed to generate token: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(200, map[string]string{"token": token})
+}
+
+// verifySecondFactor checks a TOTP or recovery code for an enabled record
+// and saves its new state, unless a concurrent request used the same code
+// first. Codes are short, so attempts are throttled like
+// passwords. It answers the request itself when the code is refused.
+func (j *JWTAuthProvider) verifySecondFactor(ctx http.Context, record *TwoFactor, code, recoveryCode string) bool {
+	guardKey := "2fa:" + record.ID
+	ip := ctx.RemoteIP()
+	if j.LoginGuard != nil {
+		if wait := j.LoginGuard.Check(guardKey, ip); wait > 0 {
+			ctx.SetHeader("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			ctx.JSON(429, map[string]string{"error": "too many failed attempts, try again later"})
+			return false
+		}
+	}
+
+	old := *record
+	valid := j.checkSecondFactor(record, code, recoveryCode)
+	if valid {
+		saved, err := j.saveSecondFactor(old, map[string]any{
+			"last_used_step": record.LastUsedStep,
+			"recovery_codes": record.RecoveryCodes,
+		})
+		if err != nil {
+			ctx.JSON(500, map[string]string{"error": "failed to update two-factor settings"})
+			return false
+		}
+		valid = saved
+	}
+
+	if !valid {
+		if j.LoginGuard != nil {
+			j.LoginGuard.Fail(guardKey, ip)
+		}
+		ctx.JSON(401, map[string]string{"error": "invalid code"})
+		return false
+	}
+
+	if j.LoginGuard != nil {
+		j.LoginGuard.Succeed(guardKey)
+	}
+	return true
+}
+
+// twoFactorChallenge returns a short-lived challenge token when the user has
+// two-factor authentication enabled, or an empty string otherwise.
+func (j *JWTAuthProvider) twoFactorChallenge(userID string) (string, error) {
+	if !j.twoFactorEnabled() {
+		return "", nil
+	}
+
+	record, err := j.findTwoFactor(userID)
+	if err != nil || record == nil || !record.Enabled {
+		return "", err
+	}
+
+	return utils.GenerateJWTWithClaims(userID, j.SecretKey, j.ChallengeTTL, map[string]any{
+		tokenTypeClaim:      tokenTypeChallenge,
+		challengeStateClaim: challengeState(record),
+	})
+}
+
+// challengeState fingerprints the parts of a record that every accepted
+// code changes.
+func challengeState(record *TwoFactor) string {
+	sum := sha256.Sum256([]byte(strconv.FormatInt(record.LastUsedStep, 10) + ":" + record.RecoveryCodes))
+	return hex.EncodeToString(sum[:16])
+}
+
+// saveSecondFactor stores values on the record only if it is still as it
+// was read: the update is filtered on the old state, so of two requests
+// with the same code, or a code and a disable, only the first one saves.
+func (j *JWTAuthProvider) saveSecondFactor(old TwoFactor, values map[string]any) (bool, error) {
+	updated, err := j.DB.UpdateWhere(&TwoFactor{}, map[string]any{
+		"id":             old.ID,
+		"enabled":        old.Enabled,
+		"last_used_step": old.LastUsedStep,
+		"recovery_codes": old.RecoveryCodes,
+	}, values)
+	return updated > 0, err
+}
+
+// checkSecondFactor validates a TOTP code, or consumes a recovery code, and
+// updates the record in memory. The caller saves it with saveSecondFactor.
+func (j *JWTAuthProvider) checkSecondFactor(record *TwoFactor, code, recoveryCode string) bool {
+	if recoveryCode != "" {
+		return consumeRecoveryCode(record, recoveryCode)
+	}
+	return j.checkTOTP(record, code)
+}
+
+func (j *JWTAuthProvider) checkTOTP(record *TwoFactor, code string) bool {
+	secret, err := j.decryptSecret(record.Secret)
+	if err != nil {
+		return false
+	}
+
+	step, ok := j.TOTP.Validate(secret, code, time.Now())
+	if !ok || step <= record.LastUsedStep {
+		return false
+	}
+
+	record.LastUsedStep = step
+	return true
+}
+
+func (j *JWTAuthProvider) findTwoFactor(userID string) (*TwoFactor, error) {
+	found, err := j.DB.FindAll(&TwoFactor{}, map[string]any{"id": userID}, db.Pagination{Limit: 1}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	records, _ := found.([]TwoFactor)
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return &records[0], nil
+}
+
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	return codes, hashes, nil
+}
+
+func consumeRecoveryCode(record *TwoFactor, code string) bool {
+	hash := hashRecoveryCode(code)
+	hashes := strings.Split(record.RecoveryCodes, ",")
+
+	for i, h := range hashes {
+		if h != "" && h == hash {
+			record.RecoveryCodes = strings.Join(append(hashes[:i], hashes[i+1:]...), ",")
+			return true
+		}
+	}
+	return false
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+func (j *JWTAuthProvider) secretCipher() (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte("gompose-totp:" + j.SecretKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (j *JWTAuthProvider) encryptSecret(secret string) (string, error) {
+	aead, err := j.secretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (j *JWTAuthProvider) decryptSecret(encrypted string) (string, error) {
+	aead, err := j.secretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("invalid secret")
+	}
+
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid secret: %w", err)
+	}
+	return string(plain), nil
+}
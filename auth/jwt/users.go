@@ -0,0 +1,17 @@
+package jwt
+
+import (
+	"github.com/Lumicrate/gompose/auth"
+)
+
+func (j *JWTAuthProvider) newUser() any {
+	return auth.NewUser(j.UserModel)
+}
+
+func (j *JWTAuthProvider) findUserByEmail(email string) (auth.AuthUser, error) {
+	return auth.FindUserByEmail(j.DB, j.UserModel, email)
+}
+
+func (j *JWTAuthProvider) findUserByID(id string) (auth.AuthUser, error) {
+	return auth.FindUserByID(j.DB, j.UserModel, id)
+}
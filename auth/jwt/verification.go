@@ -0,0 +1,199 @@
+package jwt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Lumicrate/gompose/auth"
+	"github.com/Lumicrate/gompose/http"
+)
+
+// SetMailer enables the email verification and password reset routes.
+func (j *JWTAuthProvider) SetMailer(mailer auth.Mailer) *JWTAuthProvider {
+	j.Mailer = mailer
+	return j
+}
+
+// SetRequireVerifiedEmail blocks login until the user has verified the email
+// address. The user model must implement auth.VerifiableUser.
+func (j *JWTAuthProvider) SetRequireVerifiedEmail(required bool) *JWTAuthProvider {
+	j.RequireVerifiedEmail = required
+	return j
+}
+
+// SetVerificationURL sets the link sent in verification emails. A "{token}"
+// placeholder is replaced with the token, otherwise it is appended.
+func (j *JWTAuthProvider) SetVerificationURL(url string) *JWTAuthProvider {
+	j.VerificationURL = url
+	return j
+}
+
+// SetResetURL sets the link sent in password reset emails. A "{token}"
+// placeholder is replaced with the token, otherwise it is appended.
+func (j *JWTAuthProvider) SetResetURL(url string) *JWTAuthProvider {
+	j.ResetURL = url
+	return j
+}
+
+func (j *JWTAuthProvider) sendVerificationEmail(ctx http.Context, user auth.AuthUser) error {
+	token, err := j.issueActionToken(user.GetID(), PurposeVerifyEmail, j.VerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return j.Mailer.Send(ctx.Request().Context(), auth.Message{
+		To:      user.GetEmail(),
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Use the link below to verify your email address. It expires in %s.\n\n%s",
+			j.VerificationTTL, actionLink(j.VerificationURL, token)),
+	})
+}
+
+func (j *JWTAuthProvider) verifyHandler(ctx http.Context) {
+	payload := struct {
+		Token string `json:"token"`
+	}{}
+
+	if err := ctx.BindJSON(&payload); err != nil {
+		ctx.JSON(400, map[string]string{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	userID, err := j.consumeActionToken(payload.Token, PurposeVerifyEmail)
+	if err != nil {
+		ctx.JSON(400, map[string]string{"error": err.Error()})
+		return
+	}
+
+	if err := j.markEmailVerified(userID); err != nil {
+		ctx.JSON(500, map[string]string{"error": "failed to verify email: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(200, map[string]string{"message": "email verified successfully"})
+}
+
+func (j *JWTAuthProvider) forgotPasswordHandler(ctx http.Context) {
+	payload := struct {
+		Email string `json:"email"`
+	}{}
+
+	if err := ctx.BindJSON(&payload); err != nil {
+		ctx.JSON(400, map[string]string{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	user, err := j.findUserByEmail(payload.Email)
+	if err != nil {
+		ctx.JSON(500, map[string]string{"error": "failed to query user"})
+		return
+	}
+
+	// The response is the same, and takes the same time, whether or not the
+	// address is registered, so that this route cannot be used to discover
+	// accounts: the token and the email are created after responding.
+	// The engine may recycle ctx once the handler returns, so the goroutine
+	// only gets values taken from it beforehand.
+	if user != nil {
+		reqCtx := context.WithoutCancel(ctx.Request().Context())
+		j.resets.Add(1)
+		go func() {
+			defer j.resets.Done()
+			j.sendResetEmail(reqCtx, user)
+		}()
+	}
+
+	ctx.JSON(202, map[string]string{"message": "if the address is registered, a reset link has been sent"})
+}
+
+// sendResetEmail issues a reset token and mails it. It runs after the
+// response has been sent, so failures are only logged.
+func (j *JWTAuthProvider) sendResetEmail(ctx context.Context, user auth.AuthUser) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	token, err := j.issueActionToken(user.GetID(), PurposeResetPassword, j.ResetTTL)
+	if err != nil {
+		j.logger().Error("jwt: failed to create reset token", "user_id", user.GetID(), "error", err)
+		return
+	}
+
+	err = j.Mailer.Send(ctx, auth.Message{
+		To:      user.GetEmail(),
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Use the link below to choose a new password. It expires in %s.\n\n%s",
+			j.ResetTTL, actionLink(j.ResetURL, token)),
+	})
+	if err != nil {
+		j.logger().Error("jwt: failed to send reset email", "user_id", user.GetID(), "error", err)
+	}
+}
+
+func (j *JWTAuthProvider) resetPasswordHandler(ctx http.Context) {
+	payload := struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}{}
+
+	if err := ctx.BindJSON(&payload); err != nil {
+		ctx.JSON(400, map[string]string{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	// Check the password first so that a rejected one does not burn the token.
+	if err := j.validatePassword(payload.Password); err != nil {
+		ctx.JSON(400, map[string]string{"error": err.Error()})
+		return
+	}
+
+	hashed, err := j.Hasher.Hash(payload.Password)
+	if err != nil {
+		ctx.JSON(400, map[string]string{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	userID, err := j.consumeActionToken(payload.Token, PurposeResetPassword)
+	if err != nil {
+		ctx.JSON(400, map[string]string{"error": err.Error()})
+		return
+	}
+
+	user, err := j.findUserByID(userID)
+	if err != nil {
+		ctx.JSON(400, map[string]string{"error": errInvalidActionToken.Error()})
+		return
+	}
+
+	auth.SetUserField(user, "Password", hashed)
+	// Following a link from the mailbox proves control of the address.
+	auth.SetUserField(user, "EmailVerified", true)
+
+	if err := j.DB.Update(user); err != nil {
+		ctx.JSON(500, map[string]string{"error": "failed to reset password: " + err.Error()})
+		return
+	}
+
+	ctx.JSON(200, map[string]string{"message": "password reset successfully"})
+}
+
+func (j *JWTAuthProvider) markEmailVerified(userID string) error {
+	user, err := j.findUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	auth.SetUserField(user, "EmailVerified", true)
+	return j.DB.Update(user)
+}
+
+func actionLink(base, token string) string {
+	if base == "" {
+		return token
+	}
+	if strings.Contains(base, "{token}") {
+		return strings.ReplaceAll(base, "{token}", token)
+	}
+	return base + token
+}
@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoginGuard counts failed logins per account and per client IP. Once a
+// counter reaches its limit further attempts are refused until the lockout
+// expires; below the limit every failure adds a growing delay.
+type LoginGuard struct {
+	MaxAccountFailures int
+	MaxIPFailures      int
+	Window             time.Duration // failures older than this are forgotten
+	Lockout            time.Duration
+	Delay              time.Duration // added per previous failure, capped at MaxDelay
+	MaxDelay           time.Duration
+
+	mu       sync.Mutex
+	accounts map[string]*loginFailures
+	ips      map[string]*loginFailures
+	sweptAt  time.Time
+}
+
+type loginFailures struct {
+	count       int
+	first       time.Time
+	lockedUntil time.Time
+}
+
+func NewLoginGuard() *LoginGuard {
+	return &LoginGuard{
+		MaxAccountFailures: 5,
+		MaxIPFailures:      50,
+		Window:             15 * time.Minute,
+		Lockout:            15 * time.Minute,
+		Delay:              250 * time.Millisecond,
+		MaxDelay:           2 * time.Second,
+		accounts:           make(map[string]*loginFailures),
+		ips:                make(map[string]*loginFailures),
+	}
+}
+
+// Check reports how long the caller has to wait before the account or IP may
+// try again. Zero means the attempt may proceed.
+func (g *LoginGuard) Check(account, ip string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	wait := time.Duration(0)
+	for _, f := range []*loginFailures{g.accounts[normalizeAccount(account)], g.ips[ip]} {
+		if f != nil && now.Before(f.lockedUntil) && f.lockedUntil.Sub(now) > wait {
+			wait = f.lockedUntil.Sub(now)
+		}
+	}
+	return wait
+}
+
+// Penalty returns the delay to apply before answering an attempt, based on
+// the failures already recorded for the account.
+func (g *LoginGuard) Penalty(account string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	f := g.accounts[normalizeAccount(account)]
+	if f == nil || time.Since(f.first) > g.Window {
+		return 0
+	}
+
+	delay := time.Duration(f.count) * g.Delay
+	if g.MaxDelay > 0 && delay > g.MaxDelay {
+		delay = g.MaxDelay
+	}
+	return delay
+}
+
+// Fail records a failed attempt for the account and IP.
+func (g *LoginGuard) Fail(account, ip string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.accounts == nil {
+		g.accounts = make(map[string]*loginFailures)
+		g.ips = make(map[string]*loginFailures)
+	}
+
+	now := time.Now()
+	g.sweep(now)
+	g.record(g.accounts, normalizeAccount(account), g.MaxAccountFailures, now)
+	g.record(g.ips, ip, g.MaxIPFailures, now)
+}
+
+// Succeed clears the failures of the account. IP failures are kept so that a
+// client cannot reset its counter by logging into an account it controls.
+func (g *LoginGuard) Succeed(account string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.accounts, normalizeAccount(account))
+}
+
+func (g *LoginGuard) record(counters map[string]*loginFailures, key string, limit int, now time.Time) {
+	if key == "" || limit <= 0 {
+		return
+	}
+
+	f := counters[key]
+	if f == nil || now.Sub(f.first) > g.Window {
+		f = &loginFailures{first: now}
+		counters[key] = f
+	}
+
+	f.count++
+	if f.count >= limit {
+		f.lockedUntil = now.Add(g.Lockout)
+		f.count = 0
+		f.first = now
+	}
+}
+
+// sweep drops expired counters so that the maps do not grow without bound.
+func (g *LoginGuard) sweep(now time.Time) {
+	if now.Sub(g.sweptAt) < g.Window {
+		return
+	}
+	g.sweptAt = now
+
+	for _, counters := range []map[string]*loginFailures{g.accounts, g.ips} {
+		for key, f := range counters {
+			if now.Sub(f.first) > g.Window && now.After(f.lockedUntil) {
+				delete(counters, key)
+			}
+		}
+	}
+}
+
+func normalizeAccount(account string) string {
+	return strings.ToLower(strings.TrimSpace(account))
+}
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestGuard() *LoginGuard {
+	g := NewLoginGuard()
+	g.MaxAccountFailures = 3
+	g.MaxIPFailures = 5
+	g.Window = time.Minute
+	g.Lockout = 10 * time.Minute
+	g.Delay = 100 * time.Millisecond
+	g.MaxDelay = 250 * time.Millisecond
+	return g
+}
+
+func TestLoginGuardLockout(t *testing.T) {
+	tests := []struct {
+		name     string
+		fail     func(g *LoginGuard)
+		account  string
+		ip       string
+		lockedIn bool
+	}{
+		{
+			name:     "below the account limit",
+			fail:     func(g *LoginGuard) { failN(g, 2, "a@b.c", "1.1.1.1") },
+			account:  "a@b.c",
+			ip:       "1.1.1.1",
+			lockedIn: false,
+		},
+		{
+			name:     "account limit reached",
+			fail:     func(g *LoginGuard) { failN(g, 3, "a@b.c", "1.1.1.1") },
+			account:  "a@b.c",
+			ip:       "2.2.2.2",
+			lockedIn: true,
+		},
+		{
+			name:     "accounts are normalized",
+			fail:     func(g *LoginGuard) { failN(g, 3, " A@B.c ", "1.1.1.1") },
+			account:  "a@b.c",
+			ip:       "2.2.2.2",
+			lockedIn: true,
+		},
+		{
+			name:     "other accounts are not locked",
+			fail:     func(g *LoginGuard) { failN(g, 3, "a@b.c", "1.1.1.1") },
+			account:  "x@y.z",
+			ip:       "2.2.2.2",
+			lockedIn: false,
+		},
+		{
+			name: "IP limit reached across accounts",
+			fail: func(g *LoginGuard) {
+				for _, account := range []string{"a", "b", "c", "d", "e"} {
+					g.Fail(account, "1.1.1.1")
+				}
+			},
+			account:  "x@y.z",
+			ip:       "1.1.1.1",
+			lockedIn: true,
+		},
+		{
+			name: "failures outside the window are forgotten",
+			fail: func(g *LoginGuard) {
+				failN(g, 2, "a@b.c", "1.1.1.1")
+				g.accounts["a@b.c"].first = time.Now().Add(-2 * time.Minute)
+				g.Fail("a@b.c", "1.1.1.1")
+			},
+			account:  "a@b.c",
+			ip:       "2.2.2.2",
+			lockedIn: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGuard()
+			tt.fail(g)
+
+			wait := g.Check(tt.account, tt.ip)
+			if locked := wait > 0; locked != tt.lockedIn {
+				t.Fatalf("Check = %s, want locked %v", wait, tt.lockedIn)
+			}
+			// Retry-After is derived from this wait, so it must be the
+			// remaining lockout.
+			if tt.lockedIn && (wait > g.Lockout || wait < g.Lockout-time.Second) {
+				t.Errorf("Check = %s, want about %s", wait, g.Lockout)
+			}
+		})
+	}
+}
+
+func TestLoginGuardLockExpiry(t *testing.T) {
+	g := newTestGuard()
+	failN(g, 3, "a@b.c", "1.1.1.1")
+	if g.Check("a@b.c", "1.1.1.1") == 0 {
+		t.Fatal("account is not locked")
+	}
+
+	g.accounts["a@b.c"].lockedUntil = time.Now().Add(-time.Second)
+	if wait := g.Check("a@b.c", "1.1.1.1"); wait != 0 {
+		t.Errorf("Check after the lockout = %s, want 0", wait)
+	}
+
+	// The lockout reset the counter, so one more failure does not lock
+	// the account again.
+	g.Fail("a@b.c", "1.1.1.1")
+	if wait := g.Check("a@b.c", "1.1.1.1"); wait != 0 {
+		t.Errorf("Check after one failure past the lockout = %s, want 0", wait)
+	}
+}
+
+func TestLoginGuardPenalty(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		g := newTestGuard()
+		failN(g, tt.failures, "a@b.c", "1.1.1.1")
+		if got := g.Penalty("a@b.c"); got != tt.want {
+			t.Errorf("Penalty after %d failures = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+
+	g := newTestGuard()
+	g.MaxAccountFailures = 10
+	failN(g, 5, "a@b.c", "1.1.1.1")
+	if got := g.Penalty("a@b.c"); got != g.MaxDelay {
+		t.Errorf("Penalty after 5 failures = %s, want the cap %s", got, g.MaxDelay)
+	}
+
+	g.accounts["a@b.c"].first = time.Now().Add(-2 * time.Minute)
+	if got := g.Penalty("a@b.c"); got != 0 {
+		t.Errorf("Penalty outside the window = %s, want 0", got)
+	}
+}
+
+func TestLoginGuardSucceed(t *testing.T) {
+	g := newTestGuard()
+	failN(g, 2, "a@b.c", "1.1.1.1")
+	for range 2 {
+		g.Fail("other", "1.1.1.1")
+	}
+	g.Succeed("A@b.c")
+
+	if got := g.Penalty("a@b.c"); got != 0 {
+		t.Errorf("Penalty after Succeed = %s, want 0", got)
+	}
+	// Two more failures would have locked the account without the reset.
+	failN(g, 2, "a@b.c", "2.2.2.2")
+	if wait := g.Check("a@b.c", "2.2.2.2"); wait != 0 {
+		t.Errorf("account locked after Succeed reset its counter: %s", wait)
+	}
+
+	// The IP counter is kept: 1.1.1.1 still has its 4 failures, and a
+	// fifth locks it.
+	if g.ips["1.1.1.1"].count != 4 {
+		t.Errorf("IP failures after Succeed = %d, want 4", g.ips["1.1.1.1"].count)
+	}
+	g.Fail("x", "1.1.1.1")
+	if wait := g.Check("y", "1.1.1.1"); wait == 0 {
+		t.Error("IP is not locked after reaching its limit")
+	}
+}
+
+func TestLoginGuardSweep(t *testing.T) {
+	g := newTestGuard()
+	failN(g, 1, "a@b.c", "1.1.1.1")
+	g.accounts["a@b.c"].first = time.Now().Add(-2 * time.Minute)
+	g.ips["1.1.1.1"].first = time.Now().Add(-2 * time.Minute)
+	g.sweptAt = time.Time{}
+
+	g.Fail("x@y.z", "2.2.2.2")
+	if _, ok := g.accounts["a@b.c"]; ok {
+		t.Error("expired account counter was not swept")
+	}
+	if _, ok := g.ips["1.1.1.1"]; ok {
+		t.Error("expired IP counter was not swept")
+	}
+}
+
+func failN(g *LoginGuard, n int, account, ip string) {
+	for range n {
+		g.Fail(account, ip)
+	}
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	strict := &PasswordPolicy{
+		MinLength:     10,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		Denylist:      []string{"Correct-Horse-1"},
+	}
+
+	tests := []struct {
+		name       string
+		policy     *PasswordPolicy
+		password   string
+		violations []string
+	}{
+		{"default accepts 8 characters", DefaultPasswordPolicy(), "abcdefgh", nil},
+		{"default rejects 7 characters", DefaultPasswordPolicy(), "abcdefg", []string{"must be at least 8 characters long"}},
+		{"length counts runes, not bytes", DefaultPasswordPolicy(), "ääääääää", nil},
+		{"zero value accepts anything", &PasswordPolicy{}, "", nil},
+		{"strict accepts a strong password", strict, "Tr0ub4dor&3x", nil},
+		{"missing uppercase", strict, "tr0ub4dor&3x", []string{"must contain an uppercase letter"}},
+		{"missing lowercase", strict, "TR0UB4DOR&3X", []string{"must contain a lowercase letter"}},
+		{"missing digit", strict, "Troubador&xx", []string{"must contain a digit"}},
+		{"missing symbol", strict, "Tr0ub4dor3xx", []string{"must contain a symbol"}},
+		{"space counts as a symbol", strict, "Tr0ub4dor 3x", nil},
+		{"denylist is case-insensitive", strict, "correct-horse-1", []string{"must contain an uppercase letter", "is too common"}},
+		{"every violation is listed", strict, "abc", []string{
+			"must be at least 10 characters long",
+			"must contain an uppercase letter",
+			"must contain a digit",
+			"must contain a symbol",
+		}},
+		{"common passwords", &PasswordPolicy{Denylist: CommonPasswords()}, "Password123", []string{"is too common"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate(tt.password)
+			if tt.violations == nil {
+				if err != nil {
+					t.Fatalf("Validate = %v, want nil", err)
+				}
+				return
+			}
+
+			var policyErr *PasswordPolicyError
+			if !errors.As(err, &policyErr) {
+				t.Fatalf("Validate = %v, want a *PasswordPolicyError", err)
+			}
+			if !slices.Equal(policyErr.Violations, tt.violations) {
+				t.Errorf("violations = %q, want %q", policyErr.Violations, tt.violations)
+			}
+		})
+	}
+}
@@ -0,0 +1,475 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	nethttp "net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lumicrate/gompose/auth"
+	"github.com/Lumicrate/gompose/auth/hasher"
+	"github.com/Lumicrate/gompose/db"
+	"github.com/Lumicrate/gompose/http"
+	"github.com/Lumicrate/gompose/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	errNoSession      = errors.New("missing or invalid session")
+	errInvalidCSRF    = errors.New("missing or invalid CSRF token")
+	errSessionExpired = errors.New("session expired")
+)
+
+// SessionAuthProvider authenticates browsers with an HttpOnly session cookie
+// instead of a bearer token. Unsafe methods must also send the CSRF token
+// from the CSRF cookie in the CSRF header (double-submit); the token is an
+// HMAC of the session, so it cannot be forged by a sibling domain. Login is
+// guarded the same way with a signed pre-session token from the CSRF route,
+// so that a foreign site cannot log the browser into another account.
+type SessionAuthProvider struct {
+	SecretKey  string
+	UserModel  any
+	DB         db.DBAdapter
+	Store      Store
+	Hasher     auth.PasswordHasher
+	LoginGuard *auth.LoginGuard
+
+	// LoginChecker, when set, replaces the built-in password check, so
+	// that logins follow another provider's rules.
+	LoginChecker auth.LoginChecker
+	Logger       *slog.Logger // nil uses slog.Default()
+
+	IdleTimeout time.Duration // sliding expiry, extended on every request
+	MaxLifetime time.Duration // absolute limit since login
+
+	CookieName     string
+	CSRFCookieName string
+	CSRFHeaderName string
+	CookiePath     string
+	CookieDomain   string
+	Secure         bool
+	SameSite       nethttp.SameSite
+
+	dummyHash string
+}
+
+func NewSessionAuthProvider(secretKey string, dbAdapter db.DBAdapter) *SessionAuthProvider {
+	return &SessionAuthProvider{
+		SecretKey:      secretKey,
+		UserModel:      auth.UserModel{},
+		DB:             dbAdapter,
+		Store:          NewMemoryStore(),
+		Hasher:         hasher.NewBcrypt(bcrypt.DefaultCost),
+		LoginGuard:     auth.NewLoginGuard(),
+		IdleTimeout:    time.Minute * 30,
+		MaxLifetime:    time.Hour * 24,
+		CookieName:     "gompose_session",
+		CSRFCookieName: "gompose_csrf",
+		CSRFHeaderName: "X-CSRF-Token",
+		CookiePath:     "/",
+		Secure:         true,
+		SameSite:       nethttp.SameSiteLaxMode,
+	}
+}
+
+func (s *SessionAuthProvider) SetUserModel(model any) *SessionAuthProvider {
+	if _, ok := model.(auth.AuthUser); !ok {
+		panic("SetUserModel: model must implement AuthUser interface")
+	}
+	s.UserModel = model
+	return s
+}
+
+// SetStore replaces the default in-memory store, e.g. with NewDBStore so
+// that sessions survive restarts and are shared between replicas.
+func (s *SessionAuthProvider) SetStore(store Store) *SessionAuthProvider {
+	s.Store = store
+	return s
+}
+
+func (s *SessionAuthProvider) SetPasswordHasher(h auth.PasswordHasher) *SessionAuthProvider {
+	s.Hasher = h
+	return s
+}
+
+// SetLoginChecker makes login check credentials with checker, typically the
+// JWTAuthProvider of the same app, so that its email verification and
+// two-factor rules also apply to session logins. Users with two-factor
+// authentication then send "code" or "recovery_code" with the password.
+func (s *SessionAuthProvider) SetLoginChecker(checker auth.LoginChecker) *SessionAuthProvider {
+	s.LoginChecker = checker
+	return s
+}
+
+// SetLogger sets the logger for errors that do not fail the request, such
+// as a failed password rehash. The default is slog.Default().
+func (s *SessionAuthProvider) SetLogger(logger *slog.Logger) *SessionAuthProvider {
+	s.Logger = logger
+	return s
+}
+
+func (s *SessionAuthProvider) SetIdleTimeout(d time.Duration) *SessionAuthProvider {
+	s.IdleTimeout = d
+	return s
+}
+
+func (s *SessionAuthProvider) SetMaxLifetime(d time.Duration) *SessionAuthProvider {
+	s.MaxLifetime = d
+	return s
+}
+
+// SetSecure controls the Secure cookie attribute. Only disable it for local
+// development over plain HTTP.
+func (s *SessionAuthProvider) SetSecure(secure bool) *SessionAuthProvider {
+	s.Secure = secure
+	return s
+}
+
+func (s *SessionAuthProvider) SetSameSite(mode nethttp.SameSite) *SessionAuthProvider {
+	s.SameSite = mode
+	return s
+}
+
+func (s *SessionAuthProvider) Init() error {
+	if s.SecretKey == "" {
+		return fmt.Errorf("session: SecretKey must be provided")
+	}
+
+	if s.UserModel == nil {
+		return fmt.Errorf("session: UserModel must be provided via SetUserModel")
+	}
+
+	if s.Store == nil {
+		return fmt.Errorf("session: Store must be provided via SetStore")
+	}
+
+	if s.Hasher == nil {
+		return fmt.Errorf("session: a PasswordHasher must be provided via SetPasswordHasher")
+	}
+
+	dummyHash, err := s.Hasher.Hash(utils.GenerateUUID())
+	if err != nil {
+		return fmt.Errorf("session: %w", err)
+	}
+	s.dummyHash = dummyHash
+
+	if err := s.DB.Migrate([]any{s.UserModel}); err != nil {
+		return fmt.Errorf("session: failed to migrate user model: %w", err)
+	}
+
+	if m, ok := s.Store.(interface{ Migrate() error }); ok {
+		if err := m.Migrate(); err != nil {
+			return fmt.Errorf("session: failed to migrate sessions: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Close closes the Store if it holds resources.
+func (s *SessionAuthProvider) Close() error {
+	if c, ok := s.Store.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (s *SessionAuthProvider) RegisterRoutes(engine http.HTTPEngine) {
+	engine.RegisterRoute("POST", "/auth/session/login", s.loginHandler, s.UserModel, false)
+	engine.RegisterRoute("POST", "/auth/session/logout", s.Middleware()(s.logoutHandler), nil, true)
+	engine.RegisterRoute("GET", "/auth/session/csrf", s.csrfHandler, nil, false)
+}
+
+func (s *SessionAuthProvider) loginHandler(ctx http.Context) {
+	previous := s.cookie(ctx, s.CookieName)
+	if !s.validLoginCSRF(ctx, previous) {
+		ctx.JSON(403, map[string]string{"error": errInvalidCSRF.Error()})
+		return
+	}
+
+	var creds auth.Credentials
+	if err := ctx.BindJSON(&creds); err != nil {
+		ctx.JSON(400, map[string]string{"error": "invalid input: " + err.Error()})
+		return
+	}
+
+	var user auth.AuthUser
+	var ok bool
+	if s.LoginChecker != nil {
+		user, ok = s.LoginChecker.CheckLogin(ctx, creds)
+	} else {
+		user, ok = s.checkPassword(ctx, creds.Email, creds.Password)
+	}
+	if !ok {
+		return
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		ctx.JSON(500, map[string]string{"error": "failed to create session"})
+		return
+	}
+
+	now := time.Now()
+	session := &Session{
+		ID:        hashToken(token),
+		UserID:    user.GetID(),
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.IdleTimeout),
+	}
+	if s.MaxLifetime > 0 && s.MaxLifetime < s.IdleTimeout {
+		session.ExpiresAt = now.Add(s.MaxLifetime)
+	}
+	if err := s.Store.Create(session); err != nil {
+		ctx.JSON(500, map[string]string{"error": "failed to create session: " + err.Error()})
+		return
+	}
+
+	// The new session always gets a new token; one the browser had before,
+	// possibly planted, must not stay usable.
+	if previous != "" {
+		if err := s.Store.Delete(hashToken(previous)); err != nil {
+			s.logger().Error("session: failed to delete the previous session: "+err.Error(), "user_id", user.GetID())
+		}
+	}
+
+	// The CSRF token is also returned for clients that keep it in memory.
+	csrf := s.csrfToken(token)
+	s.setCookie(ctx, s.CookieName, token, true, 0)
+	s.setCookie(ctx, s.CSRFCookieName, csrf, false, 0)
+	ctx.JSON(200, map[string]string{
+		"message":    "logged in successfully",
+		"csrf_token": csrf,
+	})
+}
+
+// checkPassword is the built-in login check, used without a LoginChecker.
+// It answers the request itself when the login is refused.
+func (s *SessionAuthProvider) checkPassword(ctx http.Context, email, password string) (auth.AuthUser, bool) {
+	ip := ctx.RemoteIP()
+	if s.LoginGuard != nil {
+		if wait := s.LoginGuard.Check(email, ip); wait > 0 {
+			ctx.SetHeader("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			ctx.JSON(429, map[string]string{"error": "too many failed login attempts, try again later"})
+			return nil, false
+		}
+	}
+
+	user, err := auth.FindUserByEmail(s.DB, s.UserModel, email)
+	if err != nil {
+		ctx.JSON(500, map[string]string{"error": "failed to query user"})
+		return nil, false
+	}
+
+	hashed := s.dummyHash
+	if user != nil {
+		hashed = user.GetHashedPassword()
+	}
+
+	if ok, _ := s.Hasher.Verify(hashed, password); !ok || user == nil {
+		if s.LoginGuard != nil {
+			s.LoginGuard.Fail(email, ip)
+		}
+		ctx.JSON(401, map[string]string{"error": "invalid username or password"})
+		return nil, false
+	}
+
+	if s.LoginGuard != nil {
+		s.LoginGuard.Succeed(email)
+	}
+
+	if err := auth.RehashIfNeeded(s.DB, s.Hasher, user, password); err != nil {
+		s.logger().Error("session: "+err.Error(), "user_id", user.GetID())
+	}
+
+	return user, true
+}
+
+func (s *SessionAuthProvider) logger() *slog.Logger {
+	if s.Logger == nil {
+		return slog.Default()
+	}
+	return s.Logger
+}
+
+func (s *SessionAuthProvider) logoutHandler(ctx http.Context) {
+	if token := s.cookie(ctx, s.CookieName); token != "" {
+		if err := s.Store.Delete(hashToken(token)); err != nil {
+			ctx.JSON(500, map[string]string{"error": "failed to end session"})
+			return
+		}
+	}
+
+	s.setCookie(ctx, s.CookieName, "", true, -1)
+	s.setCookie(ctx, s.CSRFCookieName, "", false, -1)
+	ctx.JSON(200, map[string]string{"message": "logged out successfully"})
+}
+
+// csrfHandler sets the CSRF cookie that unsafe requests have to echo in the
+// CSRF header: the session's token when logged in, otherwise a pre-session
+// token for the login request.
+func (s *SessionAuthProvider) csrfHandler(ctx http.Context) {
+	var token string
+	if _, err := s.Authenticate(ctx); err == nil {
+		token = s.csrfToken(s.cookie(ctx, s.CookieName))
+	} else {
+		nonce, err := randomToken()
+		if err != nil {
+			ctx.JSON(500, map[string]string{"error": "failed to create CSRF token"})
+			return
+		}
+		token = nonce + "." + s.csrfToken("login:"+nonce)
+	}
+
+	s.setCookie(ctx, s.CSRFCookieName, token, false, 0)
+	ctx.JSON(200, map[string]string{"csrf_token": token})
+}
+
+func (s *SessionAuthProvider) Name() string {
+	return "session"
+}
+
+func (s *SessionAuthProvider) Authenticate(ctx http.Context) (string, error) {
+	token := s.cookie(ctx, s.CookieName)
+	if token == "" {
+		return "", errNoSession
+	}
+
+	session, err := s.Store.Get(hashToken(token))
+	if err != nil {
+		return "", fmt.Errorf("failed to load session: %w", err)
+	}
+	if session == nil {
+		return "", errNoSession
+	}
+
+	now := time.Now()
+	if now.After(session.ExpiresAt) || (s.MaxLifetime > 0 && now.After(session.CreatedAt.Add(s.MaxLifetime))) {
+		_ = s.Store.Delete(session.ID)
+		return "", errSessionExpired
+	}
+
+	if !isSafeMethod(ctx.Method()) && !s.validCSRF(ctx, token) {
+		return "", errInvalidCSRF
+	}
+
+	// Slide the expiry, but write to the store at most once a minute, and
+	// never past the absolute lifetime so that stores can sweep on ExpiresAt.
+	if session.ExpiresAt.Sub(now) < s.IdleTimeout-time.Minute {
+		session.ExpiresAt = now.Add(s.IdleTimeout)
+		if s.MaxLifetime > 0 && session.ExpiresAt.After(session.CreatedAt.Add(s.MaxLifetime)) {
+			session.ExpiresAt = session.CreatedAt.Add(s.MaxLifetime)
+		}
+		if err := s.Store.Update(session); err != nil {
+			return "", fmt.Errorf("failed to refresh session: %w", err)
+		}
+	}
+
+	return session.UserID, nil
+}
+
+func (s *SessionAuthProvider) Middleware() http.MiddlewareFunc {
+	return auth.Middleware(s)
+}
+
+func (s *SessionAuthProvider) SecuritySchemes() []auth.SecurityScheme {
+	return []auth.SecurityScheme{
+		{Name: "SessionCookie", Type: "apiKey", In: "cookie", ParamName: s.CookieName},
+		{Name: "CSRFToken", Type: "apiKey", In: "header", ParamName: s.CSRFHeaderName},
+	}
+}
+
+func (s *SessionAuthProvider) SecurityRequirements() [][]string {
+	return [][]string{{"SessionCookie", "CSRFToken"}}
+}
+
+// validCSRF checks that the header matches the cookie and that both carry
+// the token derived from this session.
+func (s *SessionAuthProvider) validCSRF(ctx http.Context, sessionToken string) bool {
+	header := ctx.Header(s.CSRFHeaderName)
+	cookie := s.cookie(ctx, s.CSRFCookieName)
+	expected := s.csrfToken(sessionToken)
+
+	return header != "" &&
+		hmac.Equal([]byte(header), []byte(cookie)) &&
+		hmac.Equal([]byte(header), []byte(expected))
+}
+
+// validLoginCSRF checks the double-submit on login. The token is either a
+// pre-session token from the CSRF route or, when the browser is already
+// logged in, the token of that session.
+func (s *SessionAuthProvider) validLoginCSRF(ctx http.Context, sessionToken string) bool {
+	header := ctx.Header(s.CSRFHeaderName)
+	cookie := s.cookie(ctx, s.CSRFCookieName)
+	if header == "" || !hmac.Equal([]byte(header), []byte(cookie)) {
+		return false
+	}
+
+	if sessionToken != "" && hmac.Equal([]byte(header), []byte(s.csrfToken(sessionToken))) {
+		return true
+	}
+	nonce, mac, ok := strings.Cut(header, ".")
+	return ok && hmac.Equal([]byte(mac), []byte(s.csrfToken("login:"+nonce)))
+}
+
+func (s *SessionAuthProvider) csrfToken(sessionToken string) string {
+	mac := hmac.New(sha256.New, []byte(s.SecretKey))
+	mac.Write([]byte("csrf:" + sessionToken))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *SessionAuthProvider) cookie(ctx http.Context, name string) string {
+	c, err := ctx.Request().Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return c.Value
+}
+
+// setCookie adds a cookie to the response, next to any set before. A maxAge of 0 makes a
+// browser-session cookie, whose lifetime is enforced by the store; a
+// negative maxAge deletes the cookie.
+func (s *SessionAuthProvider) setCookie(ctx http.Context, name, value string, httpOnly bool, maxAge int) {
+	c := &nethttp.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     s.CookiePath,
+		Domain:   s.CookieDomain,
+		MaxAge:   maxAge,
+		Secure:   s.Secure,
+		HttpOnly: httpOnly,
+		SameSite: s.SameSite,
+	}
+	ctx.AddHeader("Set-Cookie", c.String())
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "TRACE":
+		return true
+	}
+	return false
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
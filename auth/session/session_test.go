@@ -0,0 +1,307 @@
+package session
+
+import (
+	"fmt"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lumicrate/gompose/auth"
+	"github.com/Lumicrate/gompose/core"
+	"github.com/Lumicrate/gompose/crud"
+	"github.com/Lumicrate/gompose/db"
+	"github.com/Lumicrate/gompose/db/sqlite"
+	nethttpadapter "github.com/Lumicrate/gompose/http/nethttp"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Note struct {
+	ID   string `gorm:"primaryKey" json:"id"`
+	Text string `json:"text"`
+}
+
+type testApp struct {
+	t        *testing.T
+	handler  nethttp.Handler
+	store    *DBStore
+	provider *SessionAuthProvider
+}
+
+func newTestApp(t *testing.T) *testApp {
+	adapter := sqlite.NewMemory()
+	store := NewDBStore(adapter)
+	provider := NewSessionAuthProvider("secret", adapter).
+		SetUserModel(&auth.UserModel{}).
+		SetStore(store).
+		SetSecure(false)
+
+	app := core.NewApp().
+		UseDB(adapter).
+		UseHTTP(nethttpadapter.New(0)).
+		UseAuth(provider).
+		AddEntity(&Note{}, crud.ProtectAll())
+	t.Cleanup(func() { _ = app.Shutdown(t.Context()) })
+	handler := app.Handler()
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err := adapter.Create(&auth.UserModel{ID: "u1", Email: "a@b.c", Password: string(hashed)}); err != nil {
+		t.Fatal(err)
+	}
+
+	return &testApp{t: t, handler: handler, store: store, provider: provider}
+}
+
+// do sends a request with the given cookies and CSRF header and returns
+// the response.
+func (a *testApp) do(method, target, body, csrf string, cookies ...*nethttp.Cookie) *nethttp.Response {
+	a.t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if csrf != "" {
+		req.Header.Set("X-CSRF-Token", csrf)
+	}
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rec := httptest.NewRecorder()
+	a.handler.ServeHTTP(rec, req)
+	return rec.Result()
+}
+
+// login fetches a pre-session CSRF token and logs in with it, returning
+// the session and CSRF cookies.
+func (a *testApp) login(cookies ...*nethttp.Cookie) (sessionCookie, csrfCookie *nethttp.Cookie) {
+	a.t.Helper()
+	csrf := cookie(a.do("GET", "/auth/session/csrf", "", "", cookies...), "gompose_csrf")
+	if csrf == nil {
+		a.t.Fatal("no CSRF cookie before login")
+	}
+
+	res := a.do("POST", "/auth/session/login", `{"email":"a@b.c","password":"password"}`, csrf.Value, append(cookies, csrf)...)
+	if res.StatusCode != 200 {
+		a.t.Fatalf("login: status %d", res.StatusCode)
+	}
+	sessionCookie, csrfCookie = cookie(res, "gompose_session"), cookie(res, "gompose_csrf")
+	if sessionCookie == nil || csrfCookie == nil {
+		a.t.Fatal("login did not set both cookies")
+	}
+	return sessionCookie, csrfCookie
+}
+
+func cookie(res *nethttp.Response, name string) *nethttp.Cookie {
+	for _, c := range res.Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestLoginCSRF(t *testing.T) {
+	a := newTestApp(t)
+	body := `{"email":"a@b.c","password":"password"}`
+	csrf := cookie(a.do("GET", "/auth/session/csrf", "", ""), "gompose_csrf")
+
+	tests := []struct {
+		name    string
+		header  string
+		cookies []*nethttp.Cookie
+		code    int
+	}{
+		{"no token", "", nil, 403},
+		{"header without cookie", csrf.Value, nil, 403},
+		{"cookie without header", "", []*nethttp.Cookie{csrf}, 403},
+		{"forged token", "nonce.mac", []*nethttp.Cookie{{Name: "gompose_csrf", Value: "nonce.mac"}}, 403},
+		{"pre-session token", csrf.Value, []*nethttp.Cookie{csrf}, 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if res := a.do("POST", "/auth/session/login", body, tt.header, tt.cookies...); res.StatusCode != tt.code {
+				t.Errorf("status %d, want %d", res.StatusCode, tt.code)
+			}
+		})
+	}
+}
+
+func TestDoubleSubmitCSRF(t *testing.T) {
+	a := newTestApp(t)
+	sess, csrf := a.login()
+	_, otherCSRF := a.login()
+	body := `{"id":"1","text":"hi"}`
+
+	tests := []struct {
+		name    string
+		header  string
+		cookies []*nethttp.Cookie
+		code    int
+	}{
+		{"no header", "", []*nethttp.Cookie{sess, csrf}, 401},
+		{"no CSRF cookie", csrf.Value, []*nethttp.Cookie{sess}, 401},
+		{"header does not match cookie", otherCSRF.Value, []*nethttp.Cookie{sess, csrf}, 401},
+		{"token of another session", otherCSRF.Value, []*nethttp.Cookie{sess, otherCSRF}, 401},
+		{"matching token", csrf.Value, []*nethttp.Cookie{sess, csrf}, 201},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if res := a.do("POST", "/notes", body, tt.header, tt.cookies...); res.StatusCode != tt.code {
+				t.Errorf("status %d, want %d", res.StatusCode, tt.code)
+			}
+		})
+	}
+
+	// Safe methods need no token.
+	if res := a.do("GET", "/notes", "", "", sess); res.StatusCode != 200 {
+		t.Errorf("GET without CSRF header: status %d, want 200", res.StatusCode)
+	}
+}
+
+func TestSlidingExpiry(t *testing.T) {
+	a := newTestApp(t)
+	sess, _ := a.login()
+	id := hashToken(sess.Value)
+
+	stored, _ := a.store.Get(id)
+	if stored == nil {
+		t.Fatal("session not stored")
+	}
+
+	// Close to expiry, a request extends the session by the idle timeout.
+	stored.ExpiresAt = time.Now().Add(time.Minute)
+	_ = a.store.Update(stored)
+	if res := a.do("GET", "/notes", "", "", sess); res.StatusCode != 200 {
+		t.Fatalf("GET: status %d", res.StatusCode)
+	}
+	stored, _ = a.store.Get(id)
+	if left := time.Until(stored.ExpiresAt); left < a.provider.IdleTimeout-time.Minute {
+		t.Errorf("session expires in %s, want about %s", left, a.provider.IdleTimeout)
+	}
+
+	// Sliding stops at the absolute lifetime.
+	stored.CreatedAt = time.Now().Add(-a.provider.MaxLifetime + 5*time.Minute)
+	stored.ExpiresAt = time.Now().Add(time.Minute)
+	_ = a.store.Update(stored)
+	a.do("GET", "/notes", "", "", sess)
+	stored, _ = a.store.Get(id)
+	if limit := stored.CreatedAt.Add(a.provider.MaxLifetime); stored.ExpiresAt.After(limit) {
+		t.Errorf("session expires at %s, after its lifetime ends at %s", stored.ExpiresAt, limit)
+	}
+
+	// An idle session is refused and deleted.
+	stored.ExpiresAt = time.Now().Add(-time.Second)
+	_ = a.store.Update(stored)
+	if res := a.do("GET", "/notes", "", "", sess); res.StatusCode != 401 {
+		t.Errorf("GET with an expired session: status %d, want 401", res.StatusCode)
+	}
+	if stored, _ := a.store.Get(id); stored != nil {
+		t.Error("expired session is still stored")
+	}
+
+	// So is one past its absolute lifetime, even while active.
+	sess, _ = a.login()
+	stored, _ = a.store.Get(hashToken(sess.Value))
+	stored.CreatedAt = time.Now().Add(-a.provider.MaxLifetime - time.Second)
+	_ = a.store.Update(stored)
+	if res := a.do("GET", "/notes", "", "", sess); res.StatusCode != 401 {
+		t.Errorf("GET past the lifetime: status %d, want 401", res.StatusCode)
+	}
+}
+
+func TestLoginRotatesSession(t *testing.T) {
+	a := newTestApp(t)
+	first, _ := a.login()
+	second, _ := a.login(first)
+
+	if second.Value == first.Value {
+		t.Fatal("login reused the session token")
+	}
+	if stored, _ := a.store.Get(hashToken(first.Value)); stored != nil {
+		t.Error("the session from before login is still stored")
+	}
+	if res := a.do("GET", "/notes", "", "", first); res.StatusCode != 401 {
+		t.Errorf("GET with the old session: status %d, want 401", res.StatusCode)
+	}
+	if res := a.do("GET", "/notes", "", "", second); res.StatusCode != 200 {
+		t.Errorf("GET with the new session: status %d, want 200", res.StatusCode)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	a := newTestApp(t)
+	sess, csrf := a.login()
+
+	if res := a.do("POST", "/auth/session/logout", "", "", sess, csrf); res.StatusCode != 401 {
+		t.Errorf("logout without CSRF header: status %d, want 401", res.StatusCode)
+	}
+
+	res := a.do("POST", "/auth/session/logout", "", csrf.Value, sess, csrf)
+	if res.StatusCode != 200 {
+		t.Fatalf("logout: status %d", res.StatusCode)
+	}
+	for _, name := range []string{"gompose_session", "gompose_csrf"} {
+		if c := cookie(res, name); c == nil || c.MaxAge >= 0 {
+			t.Errorf("logout did not clear %s", name)
+		}
+	}
+
+	if stored, _ := a.store.Get(hashToken(sess.Value)); stored != nil {
+		t.Error("session is still stored after logout")
+	}
+	if res := a.do("GET", "/notes", "", "", sess); res.StatusCode != 401 {
+		t.Errorf("GET after logout: status %d, want 401", res.StatusCode)
+	}
+}
+
+func TestDBStoreSweep(t *testing.T) {
+	adapter := sqlite.NewMemory()
+	if err := adapter.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = adapter.Close() })
+	store := NewDBStore(adapter)
+	if err := store.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	for i := range sweepBatch + 5 {
+		_ = adapter.Create(&Session{ID: fmt.Sprintf("old%d", i), ExpiresAt: now.Add(-time.Hour)})
+	}
+	_ = adapter.Create(&Session{ID: "live", ExpiresAt: now.Add(time.Hour)})
+
+	if err := store.sweep(now); err != nil {
+		t.Fatal(err)
+	}
+
+	found, _ := adapter.FindAll(&Session{}, nil, db.Pagination{}, nil)
+	sessions := found.([]Session)
+	if len(sessions) != 1 || sessions[0].ID != "live" {
+		t.Errorf("after the sweep %d sessions are left, want only the live one", len(sessions))
+	}
+}
+
+func TestLoginThrottle(t *testing.T) {
+	a := newTestApp(t)
+	a.provider.LoginGuard.Delay = 0
+	csrf := cookie(a.do("GET", "/auth/session/csrf", "", ""), "gompose_csrf")
+	wrong := `{"email":"a@b.c","password":"wrong"}`
+
+	for i := range a.provider.LoginGuard.MaxAccountFailures {
+		if res := a.do("POST", "/auth/session/login", wrong, csrf.Value, csrf); res.StatusCode != 401 {
+			t.Fatalf("failed login %d: status %d, want 401", i+1, res.StatusCode)
+		}
+	}
+
+	// Locked, even with the right password.
+	res := a.do("POST", "/auth/session/login", `{"email":"a@b.c","password":"password"}`, csrf.Value, csrf)
+	if res.StatusCode != 429 {
+		t.Fatalf("login while locked: status %d, want 429", res.StatusCode)
+	}
+	lockout := int(a.provider.LoginGuard.Lockout.Seconds())
+	if got := res.Header.Get("Retry-After"); got != fmt.Sprint(lockout) {
+		t.Errorf("Retry-After = %q, want %d", got, lockout)
+	}
+}
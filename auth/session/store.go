@@ -0,0 +1,179 @@
+package session
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Lumicrate/gompose/db"
+)
+
+// Session is a server-side login. ID is a hash of the cookie value, so the
+// store never holds anything that can be replayed as a cookie.
+type Session struct {
+	ID        string    `gorm:"primaryKey" json:"id" bson:"id"`
+	UserID    string    `gorm:"index" json:"user_id" bson:"user_id"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+}
+
+// Store keeps sessions between requests. Get returns nil when the session
+// does not exist.
+type Store interface {
+	Create(s *Session) error
+	Get(id string) (*Session, error)
+	Update(s *Session) error
+	Delete(id string) error
+}
+
+// MemoryStore keeps sessions in process memory. Sessions are lost on restart
+// and are not shared between replicas; use DBStore for that.
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+	sweptAt  time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]Session)}
+}
+
+func (m *MemoryStore) Create(s *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(time.Now())
+	m.sessions[s.ID] = *s
+	return nil
+}
+
+func (m *MemoryStore) Get(id string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+func (m *MemoryStore) Update(s *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.sessions[s.ID]; ok {
+		m.sessions[s.ID] = *s
+	}
+	return nil
+}
+
+func (m *MemoryStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, id)
+	return nil
+}
+
+// sweep drops expired sessions at most once a minute.
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.sweptAt) < time.Minute {
+		return
+	}
+	m.sweptAt = now
+
+	for id, s := range m.sessions {
+		if now.After(s.ExpiresAt) {
+			delete(m.sessions, id)
+		}
+	}
+}
+
+// sweepBatch is how many sessions DBStore reads at a time when sweeping.
+const sweepBatch = 100
+
+// DBStore keeps sessions in the application's database through a
+// db.DBAdapter. Expired sessions are deleted when they are looked up, and
+// the others by a sweep at most once a minute when a session is created.
+type DBStore struct {
+	db db.DBAdapter
+
+	mu      sync.Mutex
+	sweptAt time.Time
+}
+
+func NewDBStore(adapter db.DBAdapter) *DBStore {
+	return &DBStore{db: adapter}
+}
+
+// Migrate creates the sessions table or collection.
+func (d *DBStore) Migrate() error {
+	return d.db.Migrate([]any{&Session{}})
+}
+
+func (d *DBStore) Create(s *Session) error {
+	if err := d.db.Create(s); err != nil {
+		return err
+	}
+
+	// A failed sweep does not fail the login; the next one retries it.
+	_ = d.sweep(time.Now())
+	return nil
+}
+
+func (d *DBStore) Get(id string) (*Session, error) {
+	found, err := d.db.FindAll(&Session{}, map[string]any{"id": id}, db.Pagination{Limit: 1}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, _ := found.([]Session)
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+	if time.Now().After(sessions[0].ExpiresAt) {
+		return nil, d.db.Delete(id, &Session{})
+	}
+	return &sessions[0], nil
+}
+
+func (d *DBStore) Update(s *Session) error {
+	return d.db.Update(s)
+}
+
+func (d *DBStore) Delete(id string) error {
+	return d.db.Delete(id, &Session{})
+}
+
+// sweep deletes expired sessions, soonest expiry first. The adapters only
+// filter on equality, so it reads in batches until it meets a live session.
+func (d *DBStore) sweep(now time.Time) error {
+	d.mu.Lock()
+	if now.Sub(d.sweptAt) < time.Minute {
+		d.mu.Unlock()
+		return nil
+	}
+	d.sweptAt = now
+	d.mu.Unlock()
+
+	for {
+		found, err := d.db.FindAll(&Session{}, nil, db.Pagination{Limit: sweepBatch},
+			[]db.Sort{{Field: "expires_at", Direction: "asc"}})
+		if err != nil {
+			return err
+		}
+
+		sessions, _ := found.([]Session)
+		for _, s := range sessions {
+			if !now.After(s.ExpiresAt) {
+				return nil
+			}
+			if err := d.db.Delete(s.ID, &Session{}); err != nil {
+				return err
+			}
+		}
+		if len(sessions) < sweepBatch {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Package totp implements time-based one-time passwords as described in
+// RFC 6238 (TOTP) on top of RFC 4226 (HOTP), compatible with common
+// authenticator apps.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type Config struct {
+	Digits int           // number of digits in a code, 6 by default
+	Period time.Duration // lifetime of a code, 30 seconds by default
+	Skew   int           // number of periods accepted before and after the current one
+}
+
+func DefaultConfig() Config {
+	return Config{Digits: 6, Period: 30 * time.Second, Skew: 1}
+}
+
+// Check fills in the default Digits and Period when they are zero and
+// returns an error for values that authenticator apps do not support.
+func (c *Config) Check() error {
+	defaults := DefaultConfig()
+	if c.Digits == 0 {
+		c.Digits = defaults.Digits
+	}
+	if c.Period == 0 {
+		c.Period = defaults.Period
+	}
+
+	if c.Digits < 6 || c.Digits > 8 {
+		return fmt.Errorf("totp: Digits must be between 6 and 8, got %d", c.Digits)
+	}
+	if c.Period < time.Second {
+		return fmt.Errorf("totp: Period must be at least 1s, got %s", c.Period)
+	}
+	if c.Skew < 0 {
+		return errors.New("totp: Skew must not be negative")
+	}
+	return nil
+}
+
+// GenerateSecret returns a random 160-bit secret encoded in base32, the
+// length recommended by RFC 4226.
+func GenerateSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(secret), nil
+}
+
+// URI returns the otpauth:// URI that authenticator apps import, usually
+// shown to the user as a QR code.
+func (c Config) URI(secret, issuer, account string) string {
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(c.Digits))
+	params.Set("period", fmt.Sprint(int(c.Period.Seconds())))
+
+	// Authenticator apps expect spaces as %20 rather than "+".
+	return "otpauth://totp/" + label + "?" + strings.ReplaceAll(params.Encode(), "+", "%20")
+}
+
+// Code returns the code for the given secret at time t.
+func (c Config) Code(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, c.step(t), c.Digits), nil
+}
+
+// Validate checks a code against the current time step and the Skew steps
+// around it. It returns the matched step, which callers should remember to
+// reject a replay of the same code.
+func (c Config) Validate(secret, code string, t time.Time) (int64, bool) {
+	key, err := decodeSecret(secret)
+	if err != nil || len(code) != c.Digits {
+		return 0, false
+	}
+
+	current := c.step(t)
+	for i := -c.Skew; i <= c.Skew; i++ {
+		step := current + int64(i)
+		if subtle.ConstantTimeCompare([]byte(hotp(key, step, c.Digits)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func (c Config) step(t time.Time) int64 {
+	period := int64(c.Period / time.Second)
+	if period < 1 {
+		// Check rejects such a config; avoid dividing by zero regardless.
+		period = int64(DefaultConfig().Period / time.Second)
+	}
+	return t.Unix() / period
+}
+
+// hotp computes the RFC 4226 value for a counter using HMAC-SHA1 and
+// dynamic truncation.
+func hotp(key []byte, counter int64, digits int) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	secret = strings.TrimRight(secret, "=")
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("totp: invalid secret: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,171 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of RFC 6238 Appendix B, "12345678901234567890",
+// in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRFC6238(t *testing.T) {
+	cfg := Config{Digits: 8, Period: 30 * time.Second}
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := cfg.Code(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("Code(%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeSixDigits(t *testing.T) {
+	// The 8-digit value 94287082 truncated to 6 digits.
+	got, err := DefaultConfig().Code(rfcSecret, time.Unix(59, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "287082" {
+		t.Errorf("Code = %s, want 287082", got)
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	cfg := DefaultConfig() // Skew 1
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / 30
+
+	tests := []struct {
+		name   string
+		offset int64 // periods between the code and now
+		ok     bool
+	}{
+		{"current step", 0, true},
+		{"previous step", -1, true},
+		{"next step", 1, true},
+		{"two steps old", -2, false},
+		{"two steps ahead", 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := cfg.Code(rfcSecret, now.Add(time.Duration(tt.offset)*cfg.Period))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			step, ok := cfg.Validate(rfcSecret, code, now)
+			if ok != tt.ok {
+				t.Fatalf("Validate ok = %v, want %v", ok, tt.ok)
+			}
+			// The replay check stores this step, so it must be the step the
+			// code was made for, not the current one.
+			if ok && step != current+tt.offset {
+				t.Errorf("step = %d, want %d", step, current+tt.offset)
+			}
+		})
+	}
+}
+
+func TestValidateRejects(t *testing.T) {
+	cfg := DefaultConfig()
+	now := time.Unix(1234567890, 0)
+	code, _ := cfg.Code(rfcSecret, now)
+
+	tests := []struct {
+		name, secret, code string
+	}{
+		{"wrong code", rfcSecret, "000000"},
+		{"wrong length", rfcSecret, code[:5]},
+		{"invalid secret", "not base32!", code},
+	}
+	for _, tt := range tests {
+		if _, ok := cfg.Validate(tt.secret, tt.code, now); ok {
+			t.Errorf("%s: Validate accepted the code", tt.name)
+		}
+	}
+}
+
+func TestSecretFormatting(t *testing.T) {
+	now := time.Unix(59, 0)
+	want, _ := DefaultConfig().Code(rfcSecret, now)
+
+	// Apps show secrets in lower case and groups of four.
+	spaced := strings.ToLower(rfcSecret[:4] + " " + rfcSecret[4:8] + " " + rfcSecret[8:])
+	got, err := DefaultConfig().Code(spaced, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("Code with a formatted secret = %s, want %s", got, want)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		ok   bool
+	}{
+		{"zero value gets defaults", Config{}, true},
+		{"eight digits", Config{Digits: 8, Period: time.Minute}, true},
+		{"five digits", Config{Digits: 5}, false},
+		{"nine digits", Config{Digits: 9}, false},
+		{"sub-second period", Config{Period: 500 * time.Millisecond}, false},
+		{"negative skew", Config{Skew: -1}, false},
+	}
+
+	for _, tt := range tests {
+		cfg := tt.cfg
+		err := cfg.Check()
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: Check() = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+
+	cfg := Config{}
+	_ = cfg.Check()
+	if cfg.Digits != 6 || cfg.Period != 30*time.Second {
+		t.Errorf("defaults = %d digits every %s, want 6 every 30s", cfg.Digits, cfg.Period)
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := decodeSecret(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != 20 {
+		t.Errorf("secret has %d bytes, want 20", len(key))
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := DefaultConfig().URI(rfcSecret, "My Service", "a@b.c")
+	for _, part := range []string{"otpauth://totp/My%20Service:a@b.c?", "secret=" + rfcSecret, "issuer=My%20Service", "digits=6", "period=30"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("URI %s does not contain %s", uri, part)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/Lumicrate/gompose/db"
+)
+
+// NewUser returns a pointer to a fresh instance of the given user model.
+func NewUser(model any) any {
+	t := reflect.TypeOf(model)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return reflect.New(t).Interface()
+}
+
+// FindUserByEmail returns the user with the given email, or nil when no
+// such user exists.
+func FindUserByEmail(adapter db.DBAdapter, model any, email string) (AuthUser, error) {
+	foundUsers, err := adapter.FindAll(NewUser(model), map[string]any{
+		"email": email,
+	}, db.Pagination{Limit: 1}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	usersVal := reflect.ValueOf(foundUsers)
+	if usersVal.Len() == 0 {
+		return nil, nil
+	}
+
+	userVal := usersVal.Index(0)
+	var authUser AuthUser
+	var ok bool
+
+	if userVal.Kind() == reflect.Ptr {
+		authUser, ok = userVal.Interface().(AuthUser)
+	} else {
+		authUser, ok = userVal.Addr().Interface().(AuthUser)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("user model must implement AuthUser")
+	}
+
+	return authUser, nil
+}
+
+func FindUserByID(adapter db.DBAdapter, model any, id string) (AuthUser, error) {
+	found, err := adapter.FindByID(id, NewUser(model))
+	if err != nil {
+		return nil, err
+	}
+
+	authUser, ok := found.(AuthUser)
+	if !ok {
+		return nil, fmt.Errorf("user model must implement AuthUser")
+	}
+
+	return authUser, nil
+}
+
+// RehashIfNeeded replaces a hash made with outdated parameters after a
+// successful login, while the plain password is at hand.
+func RehashIfNeeded(adapter db.DBAdapter, hasher PasswordHasher, user AuthUser, password string) error {
+	if !hasher.NeedsRehash(user.GetHashedPassword()) {
+		return nil
+	}
+
+	hashed, err := hasher.Hash(password)
+	if err != nil {
+		return fmt.Errorf("failed to rehash password: %w", err)
+	}
+
+	SetUserField(user, "Password", hashed)
+	if err := adapter.Update(user); err != nil {
+		return fmt.Errorf("failed to save rehashed password: %w", err)
+	}
+	return nil
+}
+
+// SetUserField sets a field on a user model by name, ignoring models that
+// do not have it.
+func SetUserField(user any, name string, value any) {
+	field := reflect.ValueOf(user).Elem().FieldByName(name)
+	v := reflect.ValueOf(value)
+	if field.IsValid() && field.CanSet() && v.Type().ConvertibleTo(field.Type()) {
+		field.Set(v.Convert(field.Type()))
+	}
+}
@@ -1,14 +1,27 @@
 package core
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
+	nethttp "net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/Lumicrate/gompose/auth"
 	"github.com/Lumicrate/gompose/crud"
 	"github.com/Lumicrate/gompose/db"
 	"github.com/Lumicrate/gompose/docs/swagger"
 	"github.com/Lumicrate/gompose/http"
+	"github.com/Lumicrate/gompose/http/middlewares"
 	"github.com/Lumicrate/gompose/i18n"
+	"github.com/Lumicrate/gompose/metrics"
+	"github.com/Lumicrate/gompose/tracing"
 )
 
 type App struct {
@@ -19,6 +32,33 @@ type App struct {
 	authProvider    auth.AuthProvider
 	swaggerProvider *swagger.SwaggerProvider
 	localization    *i18n.Translator
+	logger          *slog.Logger
+	accessLog       bool
+	groups          []routeGroup
+	built           bool
+	buildOnce       sync.Once
+	buildErr        error
+	configErrs      []error // reported by Build
+
+	metrics          *metrics.Metrics
+	tracing          *tracing.Tracing
+	health           bool
+	readinessChecks  []namedCheck
+	readinessTimeout time.Duration
+
+	onStart         []func(ctx context.Context) error
+	onShutdown      []func(ctx context.Context) error
+	shutdownTimeout time.Duration
+	dbReady         bool
+	authReady       bool
+	shutdownOnce    sync.Once
+	shutdownErr     error
+}
+
+type routeGroup struct {
+	name        string
+	prefix      string
+	middlewares []http.MiddlewareFunc
 }
 
 type registeredEntity struct {
@@ -28,8 +68,10 @@ type registeredEntity struct {
 
 func NewApp() *App {
 	return &App{
-		entities:    []registeredEntity{},
-		middlewares: []http.MiddlewareFunc{},
+		entities:         []registeredEntity{},
+		middlewares:      []http.MiddlewareFunc{},
+		shutdownTimeout:  30 * time.Second,
+		readinessTimeout: 5 * time.Second,
 	}
 }
 
@@ -65,22 +107,78 @@ func (a *App) RegisterMiddleware(m http.MiddlewareFunc) *App {
 	return a
 }
 
+// AddGroup declares a route group with a path prefix and middleware that
+// only apply to its routes. Entities join it with crud.InGroup(name).
+func (a *App) AddGroup(name, prefix string, middlewares ...http.MiddlewareFunc) *App {
+	a.groups = append(a.groups, routeGroup{name: name, prefix: prefix, middlewares: middlewares})
+	return a
+}
+
 func (a *App) UseAuth(provider auth.AuthProvider) *App {
 	a.authProvider = provider
 	return a
 }
 
+// UseLogger sets the logger used by the app and its access log.
+func (a *App) UseLogger(logger *slog.Logger) *App {
+	a.logger = logger
+	return a
+}
+
+// Logger returns the logger set with UseLogger, or a JSON logger writing to
+// stdout.
+func (a *App) Logger() *slog.Logger {
+	if a.logger == nil {
+		a.logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	}
+	return a.logger
+}
+
+// UseAccessLog installs middlewares.RequestID and middlewares.AccessLog with
+// the app's logger in front of the registered middlewares.
+func (a *App) UseAccessLog() *App {
+	a.accessLog = true
+	return a
+}
+
+// OnStart adds a hook that runs after Build and before the server starts
+// listening. An error aborts Run.
+func (a *App) OnStart(hook func(ctx context.Context) error) *App {
+	a.onStart = append(a.onStart, hook)
+	return a
+}
+
+// OnShutdown adds a hook that runs after the server has drained and before
+// the auth provider and database are closed. Hooks run in reverse order.
+func (a *App) OnShutdown(hook func(ctx context.Context) error) *App {
+	a.onShutdown = append(a.onShutdown, hook)
+	return a
+}
+
+// SetShutdownTimeout sets how long Run waits for in-flight requests and
+// shutdown hooks after a signal. The default is 30 seconds.
+func (a *App) SetShutdownTimeout(d time.Duration) *App {
+	a.shutdownTimeout = d
+	return a
+}
+
+// UseI18n loads the translations in directory. A failure is reported by
+// Build.
 func (a *App) UseI18n(directory, defaultLocale string) *App {
 	var err error
 
 	if a.localization, err = i18n.NewI18n(directory, defaultLocale); err != nil {
-		log.Fatalf("i18n Init failed: %v", err)
+		a.configErrs = append(a.configErrs, &StartupError{Stage: StageI18n, Err: err})
 	}
 
 	return a
 }
 
 func (a *App) SetLocale(locale string) *App {
+	if a.localization == nil {
+		a.configErrs = append(a.configErrs, errors.New("core: SetLocale called before a successful UseI18n"))
+		return a
+	}
 	a.localization = a.localization.SetLocale(locale)
 
 	return a
@@ -90,43 +188,292 @@ func (a *App) T(messageID string, args ...any) string {
 	return a.localization.T(messageID, args...)
 }
 
+// UseMetrics records HTTP, database and authentication metrics in m and
+// serves them on GET /metrics in the Prometheus text format. Like the
+// health probes, /metrics is not behind the middlewares registered with
+// RegisterMiddleware.
+func (a *App) UseMetrics(m *metrics.Metrics) *App {
+	a.metrics = m
+	return a
+}
+
+// UseTracing starts an OpenTelemetry span for every request, continuing the
+// trace of an incoming traceparent header, with child spans for entity
+// hooks and database operations.
+func (a *App) UseTracing(t *tracing.Tracing) *App {
+	a.tracing = t
+	return a
+}
+
 func (a *App) UseSwagger() *App {
 	a.swaggerProvider = swagger.NewSwaggerProvider()
 	return a
 }
 
-func (a *App) Run() {
+// Build validates the configuration, initializes and migrates the
+// database, initializes the auth provider and registers middlewares and
+// routes on the HTTP engine, without starting a listener. It is called by
+// Run and Handler. It runs once: later calls return the first result.
+//
+// Configuration problems are all reported at once, joined, as
+// ErrNoHTTPEngine, *EntityError and *RouteError values; failures of the
+// database, auth provider or translations are *StartupError values. When
+// Build fails, the auth provider and database it initialized are closed.
+func (a *App) Build() error {
+	a.buildOnce.Do(func() {
+		if err := a.build(); err != nil {
+			a.buildErr = errors.Join(err, a.closeResources())
+			return
+		}
+		a.built = true
+	})
+	return a.buildErr
+}
+
+func (a *App) build() error {
+	if err := a.validate(); err != nil {
+		return err
+	}
+
+	if a.metrics != nil && a.dbAdapter != nil {
+		a.dbAdapter = a.metrics.WrapDB(a.dbAdapter)
+	}
+	if a.metrics != nil && a.authProvider != nil {
+		a.authProvider = a.metrics.WrapAuth(a.authProvider)
+	}
+	// Outside the metrics wrapper, so that spans include its overhead.
+	if a.tracing != nil && a.dbAdapter != nil {
+		a.dbAdapter = a.tracing.WrapDB(a.dbAdapter)
+	}
+
 	if a.dbAdapter != nil {
 		if err := a.dbAdapter.Init(); err != nil {
-			log.Fatalf("DB Init failed: %v", err)
+			return &StartupError{Stage: StageDBInit, Err: err}
 		}
+		a.dbReady = true
 
 		if err := a.dbAdapter.Migrate(a.Entities()); err != nil {
-			log.Fatalf("DB Migration failed: %v", err)
+			return &StartupError{Stage: StageMigration, Err: err}
 		}
 	}
 
 	if a.authProvider != nil {
-		err := a.authProvider.Init()
-		if err != nil {
-			return
+		if err := a.authProvider.Init(); err != nil {
+			return &StartupError{Stage: StageAuthInit, Err: err}
 		}
-		a.authProvider.RegisterRoutes(a.httpEngine)
+		a.authReady = true
+	}
+
+	var routeErrs []error
+	engine := newRouteGuard(a.httpEngine, &routeErrs)
+
+	// Middlewares apply only to routes registered after them. Tracing comes
+	// first, so that everything after it runs inside the request's span.
+	if a.tracing != nil {
+		engine.Use(a.tracing.Middleware())
+	}
+
+	if a.accessLog {
+		engine.Use(middlewares.RequestID())
+		engine.Use(middlewares.AccessLog(a.Logger()))
+	}
+
+	// Outside Recovery too, so that recovered panics are counted as 500s.
+	if a.metrics != nil {
+		engine.Use(a.metrics.Middleware())
+	}
+
+	// Inside the access log, so that recovered requests are logged as 500s.
+	engine.Use(middlewares.Recovery(a.Logger()))
+
+	// Before the user's middlewares, so that probes and scrapes are not
+	// authenticated or rate limited.
+	if a.health {
+		a.registerHealthRoutes(engine)
+	}
+	if a.metrics != nil {
+		engine.RegisterRoute("GET", "/metrics", a.metrics.Handler(), nil, false)
 	}
 
 	for _, m := range a.middlewares {
-		a.httpEngine.Use(m)
+		engine.Use(m)
+	}
+
+	if a.authProvider != nil {
+		a.authProvider.RegisterRoutes(engine)
+	}
+
+	groups := map[string]http.RouteGroup{"": engine}
+	for _, g := range a.groups {
+		group := engine.Group(g.prefix)
+		for _, m := range g.middlewares {
+			group.Use(m)
+		}
+		groups[g.name] = group
 	}
 
 	for _, e := range a.entities {
-		crud.RegisterCRUDRoutes(a.httpEngine, a.dbAdapter, e.entity, e.config, a.authProvider)
+		crud.RegisterCRUDRoutes(groups[e.config.Group], a.dbAdapter, e.entity, e.config, a.authProvider)
 	}
 
 	if a.swaggerProvider != nil {
-		a.swaggerProvider.RegisterRoutes(a.httpEngine)
+		if a.authProvider != nil {
+			a.swaggerProvider.SetAuthProvider(a.authProvider)
+		}
+		a.swaggerProvider.RegisterRoutes(engine)
+	}
+
+	return errors.Join(routeErrs...)
+}
+
+// closeResources closes the auth provider and the database, in reverse
+// order of initialization, if they were initialized.
+func (a *App) closeResources() error {
+	var errs []error
+
+	if a.authReady {
+		a.authReady = false
+		if err := a.authProvider.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("Auth Close failed: %w", err))
+		}
+	}
+
+	if a.dbReady {
+		a.dbReady = false
+		if err := a.dbAdapter.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("DB Close failed: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validate checks the configuration before anything is initialized.
+func (a *App) validate() error {
+	errs := append([]error{}, a.configErrs...)
+
+	if a.httpEngine == nil {
+		errs = append(errs, ErrNoHTTPEngine)
+	}
+
+	prefixes := map[string]string{"": ""}
+	for _, g := range a.groups {
+		if _, ok := prefixes[g.name]; ok {
+			errs = append(errs, fmt.Errorf("core: route group %q is added more than once", g.name))
+		}
+		prefixes[g.name] = g.prefix
 	}
 
-	if err := a.httpEngine.Start(); err != nil {
-		log.Fatalf("HTTP Server failed: %v", err)
+	owners := map[string]any{}
+	for _, e := range a.entities {
+		if err := crud.ValidateEntity(e.entity); err != nil {
+			errs = append(errs, &EntityError{Entity: e.entity, Reason: err.Error()})
+			continue
+		}
+
+		prefix, ok := prefixes[e.config.Group]
+		if !ok {
+			errs = append(errs, &EntityError{Entity: e.entity, Reason: fmt.Sprintf("unknown route group %q", e.config.Group)})
+			continue
+		}
+
+		path := prefix + crud.BasePath(e.entity)
+		if owner, ok := owners[path]; ok {
+			errs = append(errs, &RouteError{Method: "*", Path: path, Reason: fmt.Sprintf("used by both %T and %T", owner, e.entity)})
+			continue
+		}
+		owners[path] = e.entity
+	}
+
+	return errors.Join(errs...)
+}
+
+// Handler returns the fully wired app as an http.Handler, to mount it on an
+// existing server or drive it with httptest. It builds the app if needed
+// and panics if that fails, after closing what Build opened; call Build
+// first to handle the error.
+func (a *App) Handler() nethttp.Handler {
+	if err := a.Build(); err != nil {
+		panic(fmt.Sprintf("gompose: %v", err))
 	}
+	return a.httpEngine.Handler()
+}
+
+// Run builds the app, runs the OnStart hooks and serves until ctx is done,
+// SIGINT or SIGTERM is received or the server fails. It then shuts down
+// gracefully: see Shutdown.
+func (a *App) Run(ctx context.Context) error {
+	if err := a.Build(); err != nil {
+		return errors.Join(err, a.shutdownWithTimeout())
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for _, hook := range a.onStart {
+		if err := hook(ctx); err != nil {
+			return errors.Join(&StartupError{Stage: StageOnStart, Err: err}, a.shutdownWithTimeout())
+		}
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- a.httpEngine.Start()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if err != nil {
+			err = &StartupError{Stage: StageServer, Err: err}
+		}
+		return errors.Join(err, a.shutdownWithTimeout())
+	case <-ctx.Done():
+	}
+
+	a.Logger().Info("shutting down", slog.Duration("timeout", a.shutdownTimeout))
+	return a.shutdownWithTimeout()
+}
+
+// MustRun is Run for programs that only want to exit when it fails.
+func (a *App) MustRun(ctx context.Context) {
+	if err := a.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Shutdown stops the server, waiting for in-flight requests until ctx is
+// done, then runs the OnShutdown hooks and closes the auth provider and the
+// database, in reverse order of initialization. Only the first call has an
+// effect; later calls return its result.
+func (a *App) Shutdown(ctx context.Context) error {
+	a.shutdownOnce.Do(func() {
+		var errs []error
+
+		if a.built {
+			if err := a.httpEngine.Shutdown(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("HTTP Server shutdown failed: %w", err))
+			}
+		}
+
+		for i := len(a.onShutdown) - 1; i >= 0; i-- {
+			if err := a.onShutdown[i](ctx); err != nil {
+				errs = append(errs, fmt.Errorf("OnShutdown hook failed: %w", err))
+			}
+		}
+
+		if err := a.closeResources(); err != nil {
+			errs = append(errs, err)
+		}
+
+		a.shutdownErr = errors.Join(errs...)
+	})
+
+	return a.shutdownErr
+}
+
+func (a *App) shutdownWithTimeout() error {
+	ctx, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout)
+	defer cancel()
+
+	return a.Shutdown(ctx)
 }
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoHTTPEngine is returned by Build and Run when UseHTTP was not called.
+var ErrNoHTTPEngine = errors.New("core: no HTTP engine set, call UseHTTP")
+
+// Stage names the startup step a StartupError comes from.
+type Stage string
+
+const (
+	StageI18n      Stage = "i18n init"
+	StageDBInit    Stage = "DB init"
+	StageMigration Stage = "DB migration"
+	StageAuthInit  Stage = "auth init"
+	StageOnStart   Stage = "OnStart hook"
+	StageServer    Stage = "HTTP server"
+)
+
+// StartupError wraps an error returned by a dependency during startup.
+type StartupError struct {
+	Stage Stage
+	Err   error
+}
+
+func (e *StartupError) Error() string {
+	return fmt.Sprintf("core: %s failed: %v", e.Stage, e.Err)
+}
+
+func (e *StartupError) Unwrap() error {
+	return e.Err
+}
+
+// EntityError reports an entity that cannot be served by the CRUD routes.
+type EntityError struct {
+	Entity any
+	Reason string
+}
+
+func (e *EntityError) Error() string {
+	return fmt.Sprintf("core: entity %T: %s", e.Entity, e.Reason)
+}
+
+// RouteError reports a route that cannot be registered, usually because
+// another route already has the same method and path.
+type RouteError struct {
+	Method string
+	Path   string
+	Reason string
+}
+
+func (e *RouteError) Error() string {
+	return fmt.Sprintf("core: route %s %s: %s", e.Method, e.Path, e.Reason)
+}
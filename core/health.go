@@ -0,0 +1,129 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/Lumicrate/gompose/http"
+)
+
+// HealthCheck reports whether a dependency is ready to serve traffic.
+type HealthCheck func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check HealthCheck
+}
+
+// CheckResult is the outcome of one readiness check in the /readyz body.
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type readinessReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// UseHealth registers GET /healthz, which answers 200 while the process is
+// up, and GET /readyz, which runs the database ping and the checks added with
+// AddReadinessCheck and answers 503 if any of them fails. Both endpoints
+// skip the middlewares registered with RegisterMiddleware, such as auth and
+// rate limiting.
+func (a *App) UseHealth() *App {
+	a.health = true
+	return a
+}
+
+// AddReadinessCheck adds a named check to /readyz. Checks run concurrently
+// and are cancelled after the readiness timeout.
+func (a *App) AddReadinessCheck(name string, check HealthCheck) *App {
+	a.readinessChecks = append(a.readinessChecks, namedCheck{name: name, check: check})
+	return a
+}
+
+// SetReadinessTimeout bounds how long /readyz waits for its checks. It
+// defaults to 5 seconds.
+func (a *App) SetReadinessTimeout(d time.Duration) *App {
+	a.readinessTimeout = d
+	return a
+}
+
+func (a *App) registerHealthRoutes(engine http.HTTPEngine) {
+	engine.RegisterRoute("GET", "/healthz", func(ctx http.Context) {
+		ctx.JSON(200, map[string]string{"status": "ok"})
+	}, nil, false)
+
+	engine.RegisterRoute("GET", "/readyz", func(ctx http.Context) {
+		report := a.checkReadiness(ctx.Request().Context())
+
+		code := 200
+		if report.Status != "ok" {
+			code = 503
+		}
+		ctx.JSON(code, report)
+	}, nil, false)
+}
+
+func (a *App) checkReadiness(parent context.Context) readinessReport {
+	checks := a.readinessChecks
+	if a.dbAdapter != nil {
+		checks = append([]namedCheck{{name: "database", check: a.dbAdapter.Ping}}, checks...)
+	}
+
+	ctx, cancel := context.WithTimeout(parent, a.readinessTimeout)
+	defer cancel()
+
+	type indexed struct {
+		i      int
+		result CheckResult
+	}
+	done := make(chan indexed, len(checks))
+	for i, c := range checks {
+		go func() {
+			done <- indexed{i, runCheck(ctx, c.check)}
+		}()
+	}
+
+	// Checks that ignore ctx are reported as timed out instead of holding
+	// the probe open.
+	results := make([]CheckResult, len(checks))
+	for i := range results {
+		results[i] = CheckResult{Status: "error", Error: "timed out", LatencyMS: float64(a.readinessTimeout.Milliseconds())}
+	}
+collect:
+	for range checks {
+		select {
+		case r := <-done:
+			results[r.i] = r.result
+		case <-ctx.Done():
+			break collect
+		}
+	}
+
+	report := readinessReport{Status: "ok", Checks: make(map[string]CheckResult, len(checks))}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != "ok" {
+			report.Status = "unavailable"
+		}
+	}
+	return report
+}
+
+func runCheck(ctx context.Context, check HealthCheck) (result CheckResult) {
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			result = CheckResult{Status: "error", Error: "check panicked"}
+		}
+		result.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+	}()
+
+	if err := check(ctx); err != nil {
+		return CheckResult{Status: "error", Error: err.Error()}
+	}
+	return CheckResult{Status: "ok"}
+}
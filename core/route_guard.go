@@ -0,0 +1,52 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/Lumicrate/gompose/http"
+)
+
+// routeGuard wraps the engine during Build and turns duplicate routes and
+// registration panics into RouteErrors instead of crashing.
+type routeGuard struct {
+	http.HTTPEngine
+	group  http.RouteGroup
+	prefix string
+	seen   map[string]bool
+	errs   *[]error
+}
+
+func newRouteGuard(engine http.HTTPEngine, errs *[]error) *routeGuard {
+	return &routeGuard{HTTPEngine: engine, group: engine, seen: map[string]bool{}, errs: errs}
+}
+
+func (g *routeGuard) RegisterRoute(method string, path string, handler http.HandlerFunc, entity any, isProtected bool) {
+	fullPath := g.prefix + path
+	key := method + " " + fullPath
+	if g.seen[key] {
+		*g.errs = append(*g.errs, &RouteError{Method: method, Path: fullPath, Reason: "registered more than once"})
+		return
+	}
+	g.seen[key] = true
+
+	defer func() {
+		if r := recover(); r != nil {
+			*g.errs = append(*g.errs, &RouteError{Method: method, Path: fullPath, Reason: fmt.Sprint(r)})
+		}
+	}()
+	g.group.RegisterRoute(method, path, handler, entity, isProtected)
+}
+
+func (g *routeGuard) Use(middleware http.MiddlewareFunc) {
+	g.group.Use(middleware)
+}
+
+func (g *routeGuard) Group(prefix string) http.RouteGroup {
+	return &routeGuard{
+		HTTPEngine: g.HTTPEngine,
+		group:      g.group.Group(prefix),
+		prefix:     g.prefix + prefix,
+		seen:       g.seen,
+		errs:       g.errs,
+	}
+}
@@ -1,13 +1,27 @@
 package crud
 
+import (
+	"time"
+
+	"github.com/Lumicrate/gompose/http"
+	"github.com/Lumicrate/gompose/http/middlewares"
+)
+
+var allMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
 type Config struct {
 	ProtectedMethods map[string]bool
+	Middlewares      map[string][]http.MiddlewareFunc // per method, run after authentication
+	Group            string                           // name of the app route group, "" for the root
 }
 
 type Option func(*Config)
 
 func DefaultConfig() *Config {
-	return &Config{ProtectedMethods: make(map[string]bool)}
+	return &Config{
+		ProtectedMethods: make(map[string]bool),
+		Middlewares:      make(map[string][]http.MiddlewareFunc),
+	}
 }
 
 func Protect(methods ...string) Option {
@@ -19,5 +33,54 @@ func Protect(methods ...string) Option {
 }
 
 func ProtectAll() Option {
-	return Protect("GET", "POST", "PUT", "PATCH", "DELETE")
+	return Protect(allMethods...)
+}
+
+// WithMiddleware adds middleware to all of the entity's routes.
+func WithMiddleware(middlewares ...http.MiddlewareFunc) Option {
+	return use(nil, middlewares...)
+}
+
+// WithMethodMiddleware adds middleware to the entity's routes for one method.
+func WithMethodMiddleware(method string, middlewares ...http.MiddlewareFunc) Option {
+	return use([]string{method}, middlewares...)
+}
+
+// InGroup registers the entity's routes in a route group added with
+// App.AddGroup, under the group's prefix and behind its middleware.
+func InGroup(name string) Option {
+	return func(c *Config) {
+		c.Group = name
+	}
+}
+
+// RateLimit limits the entity's routes for the given methods, or for all of
+// them when none are given. The methods share one limiter, so use
+// middlewares.KeyByRoute in the key to count each route separately.
+func RateLimit(cfg middlewares.RateLimitConfig, methods ...string) Option {
+	return use(methods, middlewares.RateLimit(cfg))
+}
+
+// BodyLimit sets the maximum request body size of the entity's routes for
+// the given methods, or for all of them. It overrides a global BodyLimit.
+func BodyLimit(maxBytes int64, methods ...string) Option {
+	return use(methods, middlewares.BodyLimit(maxBytes))
+}
+
+// Timeout sets the request timeout of the entity's routes for the given
+// methods, or for all of them. It overrides a global Timeout.
+func Timeout(d time.Duration, methods ...string) Option {
+	return use(methods, middlewares.Timeout(d))
+}
+
+func use(methods []string, mws ...http.MiddlewareFunc) Option {
+	if len(methods) == 0 {
+		methods = allMethods
+	}
+
+	return func(c *Config) {
+		for _, method := range methods {
+			c.Middlewares[method] = append(c.Middlewares[method], mws...)
+		}
+	}
 }
@@ -1,10 +1,17 @@
 package crud
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"github.com/Lumicrate/gompose/db"
 	"github.com/Lumicrate/gompose/hooks"
 	"github.com/Lumicrate/gompose/http"
+	"github.com/Lumicrate/gompose/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	nethttp "net/http"
 	"reflect"
 	"strconv"
 	"strings"
@@ -45,6 +52,14 @@ func handleGetAll(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
 
 	result, err := dbAdapter.FindAll(entity, filters, pagination, sort)
 	if err != nil {
+		var fieldErr *db.FieldError
+		if errors.As(err, &fieldErr) {
+			ctx.JSON(400, map[string]string{"error": fieldErr.Error()})
+			return
+		}
+		if limitError(ctx, err) {
+			return
+		}
 		ctx.JSON(500, map[string]string{"error": err.Error()})
 		return
 	}
@@ -63,6 +78,9 @@ func handleGetByID(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
 
 	found, err := dbAdapter.FindByID(id, newEntity)
 	if err != nil {
+		if limitError(ctx, err) {
+			return
+		}
 		ctx.JSON(404, map[string]string{"error": "entity not found"})
 		return
 	}
@@ -78,24 +96,30 @@ func handleCreate(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
 	newEntity := reflect.New(t).Interface()
 
 	if err := ctx.Bind(newEntity); err != nil {
+		if limitError(ctx, err) {
+			return
+		}
 		ctx.JSON(400, map[string]string{"error": "invalid input" + err.Error()})
 		return
 	}
 
 	if hook, ok := newEntity.(hooks.BeforeCreate); ok {
-		if err := hook.BeforeCreate(); err != nil {
+		if err := runHook(ctx, "BeforeCreate", newEntity, hook.BeforeCreate); err != nil {
 			ctx.JSON(400, map[string]string{"error": "beforeSave failed: " + err.Error()})
 			return
 		}
 	}
 
 	if err := dbAdapter.Create(newEntity); err != nil {
+		if limitError(ctx, err) {
+			return
+		}
 		ctx.JSON(500, map[string]string{"error": err.Error()})
 		return
 	}
 
 	if hook, ok := newEntity.(hooks.AfterCreate); ok {
-		if err := hook.AfterCreate(); err != nil {
+		if err := runHook(ctx, "AfterCreate", newEntity, hook.AfterCreate); err != nil {
 			ctx.JSON(400, map[string]string{"error": "afterSave failed: " + err.Error()})
 		}
 	}
@@ -112,6 +136,9 @@ func handleUpdate(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
 	updatedEntity := reflect.New(t).Interface()
 
 	if err := ctx.Bind(updatedEntity); err != nil {
+		if limitError(ctx, err) {
+			return
+		}
 		ctx.JSON(400, map[string]string{"error": "invalid input"})
 		return
 	}
@@ -120,19 +147,22 @@ func handleUpdate(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
 	setEntityID(updatedEntity, id)
 
 	if hook, ok := updatedEntity.(hooks.BeforeUpdate); ok {
-		if err := hook.BeforeUpdate(); err != nil {
+		if err := runHook(ctx, "BeforeUpdate", updatedEntity, hook.BeforeUpdate); err != nil {
 			ctx.JSON(400, map[string]string{"error": "beforeUpdate failed: " + err.Error()})
 			return
 		}
 	}
 
 	if err := dbAdapter.Update(updatedEntity); err != nil {
+		if limitError(ctx, err) {
+			return
+		}
 		ctx.JSON(500, map[string]string{"error": err.Error()})
 		return
 	}
 
 	if hook, ok := updatedEntity.(hooks.AfterUpdate); ok {
-		if err := hook.AfterUpdate(); err != nil {
+		if err := runHook(ctx, "AfterUpdate", updatedEntity, hook.AfterUpdate); err != nil {
 			ctx.JSON(400, map[string]string{"error": "afterUpdate failed: " + err.Error()})
 			return
 		}
@@ -151,12 +181,18 @@ func handlePatch(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
 
 	found, err := dbAdapter.FindByID(id, existingEntity)
 	if err != nil {
+		if limitError(ctx, err) {
+			return
+		}
 		ctx.JSON(404, map[string]string{"error": "entity not found"})
 		return
 	}
 
 	patchData := map[string]interface{}{}
 	if err := ctx.BindJSON(&patchData); err != nil {
+		if limitError(ctx, err) {
+			return
+		}
 		ctx.JSON(400, map[string]string{"error": "invalid patch data"})
 		return
 	}
@@ -168,19 +204,22 @@ func handlePatch(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
 	}
 
 	if hook, ok := existingEntity.(hooks.BeforePatch); ok {
-		if err := hook.BeforePatch(); err != nil {
+		if err := runHook(ctx, "BeforePatch", existingEntity, hook.BeforePatch); err != nil {
 			ctx.JSON(400, map[string]string{"error": "beforePatch failed: " + err.Error()})
 			return
 		}
 	}
 
 	if err := dbAdapter.Update(found); err != nil {
+		if limitError(ctx, err) {
+			return
+		}
 		ctx.JSON(500, map[string]string{"error": err.Error()})
 		return
 	}
 
 	if hook, ok := existingEntity.(hooks.AfterPatch); ok {
-		if err := hook.AfterPatch(); err != nil {
+		if err := runHook(ctx, "AfterPatch", existingEntity, hook.AfterPatch); err != nil {
 			ctx.JSON(400, map[string]string{"error": "afterPatch failed: " + err.Error()})
 			return
 		}
@@ -199,19 +238,22 @@ func handleDelete(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
 	toDeleteEntity := reflect.New(t).Interface()
 
 	if hook, ok := toDeleteEntity.(hooks.BeforeDelete); ok {
-		if err := hook.BeforeDelete(); err != nil {
+		if err := runHook(ctx, "BeforeDelete", toDeleteEntity, hook.BeforeDelete); err != nil {
 			ctx.JSON(400, map[string]string{"error": "beforeDelete failed: " + err.Error()})
 			return
 		}
 	}
 
 	if err := dbAdapter.Delete(id, toDeleteEntity); err != nil {
+		if limitError(ctx, err) {
+			return
+		}
 		ctx.JSON(500, map[string]string{"error": err.Error()})
 		return
 	}
 
 	if hook, ok := toDeleteEntity.(hooks.AfterDelete); ok {
-		if err := hook.AfterDelete(); err != nil {
+		if err := runHook(ctx, "AfterDelete", toDeleteEntity, hook.AfterDelete); err != nil {
 			ctx.JSON(400, map[string]string{"error": "afterDelete failed: " + err.Error()})
 			return
 		}
@@ -220,6 +262,41 @@ func handleDelete(ctx http.Context, dbAdapter db.DBAdapter, entity any) {
 	ctx.JSON(204, nil)
 }
 
+// runHook runs an entity hook in a child span of the request, so that slow
+// or failing hooks show up in traces.
+func runHook(ctx http.Context, name string, entity any, hook func() error) error {
+	parent := ctx.Request().Context()
+	tracer := trace.SpanFromContext(parent).TracerProvider().Tracer(tracing.ScopeName)
+
+	_, span := tracer.Start(parent, "hook "+name, trace.WithAttributes(
+		tracing.EntityKey.String(tracing.EntityName(entity)),
+		attribute.String("gompose.hook", name),
+	))
+	defer span.End()
+
+	err := hook()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// limitError answers errors caused by middlewares.BodyLimit and
+// middlewares.Timeout and reports whether it did.
+func limitError(ctx http.Context, err error) bool {
+	var tooLarge *nethttp.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		ctx.JSON(413, map[string]string{"error": "request body too large"})
+	case errors.Is(err, context.DeadlineExceeded):
+		ctx.JSON(504, map[string]string{"error": "request timed out"})
+	default:
+		return false
+	}
+	return true
+}
+
 func setEntityID(entity any, id string) {
 	v := reflect.ValueOf(entity)
 	if v.Kind() == reflect.Ptr {
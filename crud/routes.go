@@ -1,6 +1,8 @@
 package crud
 
 import (
+	"errors"
+	"fmt"
 	"github.com/Lumicrate/gompose/auth"
 	"github.com/Lumicrate/gompose/db"
 	"github.com/Lumicrate/gompose/http"
@@ -9,55 +11,86 @@ import (
 	"strings"
 )
 
+// BasePath returns the path of an entity's collection, e.g. "/users".
+func BasePath(entity any) string {
+	t := reflect.TypeOf(entity)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return "/" + strings.ToLower(utils.Pluralize(t.Name()))
+}
+
+// ValidateEntity reports why an entity cannot be served by the CRUD routes:
+// it must be a named struct, or a pointer to one, with an ID field of a
+// type the handlers can set from the URL.
+func ValidateEntity(entity any) error {
+	t := reflect.TypeOf(entity)
+	if t == nil {
+		return errors.New("entity is nil")
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t.Name() == "" {
+		return errors.New("must be a named struct or a pointer to one")
+	}
+
+	id, ok := t.FieldByName("ID")
+	if !ok {
+		return errors.New("has no ID field")
+	}
+	switch id.Type.Kind() {
+	case reflect.String, reflect.Int, reflect.Int32, reflect.Int64:
+	default:
+		return fmt.Errorf("ID field of type %s is not supported, use a string or int", id.Type)
+	}
+	return nil
+}
+
 func RegisterCRUDRoutes(
-	engine http.HTTPEngine,
+	router http.RouteGroup,
 	dbAdapter db.DBAdapter,
 	entity any,
 	config *Config,
 	authProvider auth.AuthProvider,
 ) {
-	t := reflect.TypeOf(entity)
-	if t.Kind() == reflect.Ptr {
-		t = t.Elem()
-	}
-	entityName := t.Name()
-	basePath := "/" + strings.ToLower(utils.Pluralize(entityName))
+	basePath := BasePath(entity)
 
 	register := func(method, path string, handler http.HandlerFunc) {
-		wrapped := handler
+		wrapped := http.Chain(config.Middlewares[method], handler)
 		if config.ProtectedMethods[method] && authProvider != nil {
-			wrapped = authProvider.Middleware()(handler)
+			wrapped = authProvider.Middleware()(wrapped)
 		}
-		engine.RegisterRoute(method, path, wrapped, entity, config.ProtectedMethods[method])
+		router.RegisterRoute(method, path, wrapped, entity, config.ProtectedMethods[method])
 	}
 
 	// GET /entities (list)
 	register("GET", basePath, func(ctx http.Context) {
-		handleGetAll(ctx, dbAdapter, entity)
+		handleGetAll(ctx, db.WithContext(dbAdapter, ctx.Request().Context()), entity)
 	})
 
 	// GET /entities/:id
 	register("GET", basePath+"/:id", func(ctx http.Context) {
-		handleGetByID(ctx, dbAdapter, entity)
+		handleGetByID(ctx, db.WithContext(dbAdapter, ctx.Request().Context()), entity)
 	})
 
 	// POST /entities
 	register("POST", basePath, func(ctx http.Context) {
-		handleCreate(ctx, dbAdapter, entity)
+		handleCreate(ctx, db.WithContext(dbAdapter, ctx.Request().Context()), entity)
 	})
 
 	// PUT /entities/:id
 	register("PUT", basePath+"/:id", func(ctx http.Context) {
-		handleUpdate(ctx, dbAdapter, entity)
+		handleUpdate(ctx, db.WithContext(dbAdapter, ctx.Request().Context()), entity)
 	})
 
 	// PATCH /entities/:id
 	register("PATCH", basePath+"/:id", func(ctx http.Context) {
-		handlePatch(ctx, dbAdapter, entity)
+		handlePatch(ctx, db.WithContext(dbAdapter, ctx.Request().Context()), entity)
 	})
 
 	// DELETE /entities/:id
 	register("DELETE", basePath+"/:id", func(ctx http.Context) {
-		handleDelete(ctx, dbAdapter, entity)
+		handleDelete(ctx, db.WithContext(dbAdapter, ctx.Request().Context()), entity)
 	})
 }
@@ -0,0 +1,18 @@
+package db
+
+import "context"
+
+// ContextAdapter is implemented by adapters that can bind their queries to a
+// context, so that they stop when the request is cancelled or times out.
+type ContextAdapter interface {
+	WithContext(ctx context.Context) DBAdapter
+}
+
+// WithContext returns the adapter bound to ctx, or the adapter itself if it
+// does not implement ContextAdapter.
+func WithContext(adapter DBAdapter, ctx context.Context) DBAdapter {
+	if c, ok := adapter.(ContextAdapter); ok {
+		return c.WithContext(ctx)
+	}
+	return adapter
+}
@@ -0,0 +1,299 @@
+// Package dbtest checks that a db.DBAdapter follows the contract the crud
+// handlers rely on: CRUD round trips, conditional updates, FindAll filters,
+// sorting and pagination, typed results, context cancellation and Ping.
+//
+// Adapters call it from their own tests:
+//
+//	func TestAdapter(t *testing.T) {
+//		if err := dbtest.TestAdapter(sqlite.NewMemory()); err != nil {
+//			t.Fatal(err)
+//		}
+//	}
+package dbtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/Lumicrate/gompose/db"
+)
+
+// Record is the entity the checks store. TestAdapter migrates it and
+// deletes every existing record first, so point it at a disposable
+// database.
+type Record struct {
+	ID   string `gorm:"primaryKey" json:"id" bson:"id"`
+	Name string `json:"name" bson:"name"`
+	Age  int    `json:"age" bson:"age"`
+}
+
+var seed = []Record{
+	{ID: "a", Name: "alice", Age: 30},
+	{ID: "b", Name: "bob", Age: 40},
+	{ID: "c", Name: "carol", Age: 50},
+}
+
+type check struct {
+	name string
+	run  func(adapter db.DBAdapter) error
+}
+
+// The checks run in order on the same data; later ones rely on the
+// records created by the first.
+var checks = []check{
+	{"ping", checkPing},
+	{"create and find by id", checkCreate},
+	{"find all", checkFindAll},
+	{"filter", checkFilter},
+	{"sort", checkSort},
+	{"pagination", checkPagination},
+	{"update", checkUpdate},
+	{"update where", checkUpdateWhere},
+	{"delete", checkDelete},
+	{"missing id", checkMissing},
+	{"context", checkContext},
+}
+
+// TestAdapter initializes adapter, runs every check and closes it. It
+// returns the failures joined into one error, or nil.
+func TestAdapter(adapter db.DBAdapter) error {
+	if err := adapter.Init(); err != nil {
+		return fmt.Errorf("init: %w", err)
+	}
+	defer adapter.Close()
+
+	if err := adapter.Migrate([]any{&Record{}}); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	if err := deleteAll(adapter); err != nil {
+		return fmt.Errorf("deleting existing records: %w", err)
+	}
+
+	var errs []error
+	for _, c := range checks {
+		if err := c.run(adapter); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func deleteAll(adapter db.DBAdapter) error {
+	records, err := findAll(adapter, nil, db.Pagination{}, nil)
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := adapter.Delete(r.ID, &Record{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkPing(adapter db.DBAdapter) error {
+	return adapter.Ping(context.Background())
+}
+
+func checkCreate(adapter db.DBAdapter) error {
+	for _, r := range seed {
+		if err := adapter.Create(&r); err != nil {
+			return err
+		}
+	}
+
+	got, err := findByID(adapter, "b")
+	if err != nil {
+		return err
+	}
+	if got != seed[1] {
+		return fmt.Errorf("got %+v, want %+v", got, seed[1])
+	}
+	return nil
+}
+
+func checkFindAll(adapter db.DBAdapter) error {
+	records, err := findAll(adapter, nil, db.Pagination{}, nil)
+	if err != nil {
+		return err
+	}
+	if len(records) != len(seed) {
+		return fmt.Errorf("got %d records, want %d", len(records), len(seed))
+	}
+	return nil
+}
+
+func checkFilter(adapter db.DBAdapter) error {
+	records, err := findAll(adapter, map[string]any{"name": "bob"}, db.Pagination{}, nil)
+	if err != nil {
+		return err
+	}
+	return expectIDs(records, "b")
+}
+
+func checkSort(adapter db.DBAdapter) error {
+	records, err := findAll(adapter, nil, db.Pagination{}, []db.Sort{{Field: "age", Direction: "desc"}})
+	if err != nil {
+		return err
+	}
+	if err := expectIDs(records, "c", "b", "a"); err != nil {
+		return fmt.Errorf("desc: %w", err)
+	}
+
+	records, err = findAll(adapter, nil, db.Pagination{}, []db.Sort{{Field: "name", Direction: "asc"}})
+	if err != nil {
+		return err
+	}
+	if err := expectIDs(records, "a", "b", "c"); err != nil {
+		return fmt.Errorf("asc: %w", err)
+	}
+	return nil
+}
+
+func checkPagination(adapter db.DBAdapter) error {
+	sort := []db.Sort{{Field: "age", Direction: "asc"}}
+
+	records, err := findAll(adapter, nil, db.Pagination{Limit: 2}, sort)
+	if err != nil {
+		return err
+	}
+	if err := expectIDs(records, "a", "b"); err != nil {
+		return fmt.Errorf("limit: %w", err)
+	}
+
+	records, err = findAll(adapter, nil, db.Pagination{Limit: 2, Offset: 2}, sort)
+	if err != nil {
+		return err
+	}
+	if err := expectIDs(records, "c"); err != nil {
+		return fmt.Errorf("offset: %w", err)
+	}
+	return nil
+}
+
+func checkUpdate(adapter db.DBAdapter) error {
+	want := Record{ID: "a", Name: "alicia", Age: 31}
+	if err := adapter.Update(&want); err != nil {
+		return err
+	}
+
+	got, err := findByID(adapter, "a")
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("got %+v, want %+v", got, want)
+	}
+	return nil
+}
+
+// checkUpdateWhere updates c only while its age is still 50, as a claim
+// would: the second call must find nothing to update.
+func checkUpdateWhere(adapter db.DBAdapter) error {
+	filters := map[string]any{"id": "c", "age": 50}
+	values := map[string]any{"age": 51}
+
+	n, err := adapter.UpdateWhere(&Record{}, filters, values)
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return fmt.Errorf("updated %d records, want 1", n)
+	}
+
+	n, err = adapter.UpdateWhere(&Record{}, filters, values)
+	if err != nil {
+		return err
+	}
+	if n != 0 {
+		return fmt.Errorf("updated %d records once the filter no longer matches, want 0", n)
+	}
+
+	got, err := findByID(adapter, "c")
+	if err != nil {
+		return err
+	}
+	if want := (Record{ID: "c", Name: "carol", Age: 51}); got != want {
+		return fmt.Errorf("got %+v, want %+v", got, want)
+	}
+	return nil
+}
+
+func checkDelete(adapter db.DBAdapter) error {
+	if err := adapter.Delete("b", &Record{}); err != nil {
+		return err
+	}
+	if _, err := findByID(adapter, "b"); err == nil {
+		return errors.New("deleted record is still found")
+	}
+
+	records, err := findAll(adapter, nil, db.Pagination{}, []db.Sort{{Field: "age", Direction: "asc"}})
+	if err != nil {
+		return err
+	}
+	return expectIDs(records, "a", "c")
+}
+
+func checkMissing(adapter db.DBAdapter) error {
+	if _, err := adapter.FindByID("missing", &Record{}); err == nil {
+		return errors.New("FindByID returned no error for a missing record")
+	}
+	return nil
+}
+
+// checkContext only applies to adapters implementing db.ContextAdapter.
+func checkContext(adapter db.DBAdapter) error {
+	if _, ok := adapter.(db.ContextAdapter); !ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := findAll(db.WithContext(adapter, ctx), nil, db.Pagination{}, nil); err == nil {
+		return errors.New("FindAll succeeded with a cancelled context")
+	}
+	if _, err := findAll(adapter, nil, db.Pagination{}, nil); err != nil {
+		return fmt.Errorf("the original adapter is affected by WithContext: %w", err)
+	}
+	return nil
+}
+
+func findAll(adapter db.DBAdapter, filters map[string]any, pagination db.Pagination, sort []db.Sort) ([]Record, error) {
+	result, err := adapter.FindAll(&Record{}, filters, pagination, sort)
+	if err != nil {
+		return nil, err
+	}
+
+	records, ok := result.([]Record)
+	if !ok {
+		return nil, fmt.Errorf("FindAll returned %s, want []dbtest.Record", reflect.TypeOf(result))
+	}
+	return records, nil
+}
+
+func findByID(adapter db.DBAdapter, id string) (Record, error) {
+	result, err := adapter.FindByID(id, &Record{})
+	if err != nil {
+		return Record{}, err
+	}
+
+	record, ok := result.(*Record)
+	if !ok {
+		return Record{}, fmt.Errorf("FindByID returned %s, want *dbtest.Record", reflect.TypeOf(result))
+	}
+	return *record, nil
+}
+
+func expectIDs(records []Record, ids ...string) error {
+	got := make([]string, len(records))
+	for i, r := range records {
+		got[i] = r.ID
+	}
+	if !reflect.DeepEqual(got, ids) {
+		return fmt.Errorf("got ids %v, want %v", got, ids)
+	}
+	return nil
+}
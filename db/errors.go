@@ -0,0 +1,14 @@
+package db
+
+import "fmt"
+
+// FieldError is returned by FindAll when a filter or sort key is not a
+// column of the entity. The keys come from query parameters, so the CRUD
+// handlers answer it with 400.
+type FieldError struct {
+	Field string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("unknown field %q", e.Field)
+}
@@ -0,0 +1,200 @@
+// Package gormdb implements db.DBAdapter on top of GORM. The SQL adapters
+// (postgres, mysql, sqlite) embed Adapter and differ only in the dialector they
+// open and how they tune the connection pool.
+package gormdb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Lumicrate/gompose/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Adapter struct {
+	name      string
+	dialector gorm.Dialector
+	db        *gorm.DB
+}
+
+// New returns an adapter that opens dialector on Init. name prefixes its
+// errors, e.g. "postgres".
+func New(name string, dialector gorm.Dialector) *Adapter {
+	return &Adapter{name: name, dialector: dialector}
+}
+
+func (a *Adapter) Init() error {
+	var err error
+	a.db, err = gorm.Open(a.dialector, &gorm.Config{})
+	return err
+}
+
+// DB returns the underlying GORM handle, or nil before Init.
+func (a *Adapter) DB() *gorm.DB {
+	return a.db
+}
+
+// Close closes the connection pool.
+func (a *Adapter) Close() error {
+	if a.db == nil {
+		return nil
+	}
+	sqlDB, err := a.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// Ping checks that the database is reachable.
+func (a *Adapter) Ping(ctx context.Context) error {
+	if a.db == nil {
+		return fmt.Errorf("%s: not initialized", a.name)
+	}
+	sqlDB, err := a.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// WithContext returns a copy of the adapter whose queries use ctx.
+func (a *Adapter) WithContext(ctx context.Context) db.DBAdapter {
+	if a.db == nil {
+		return a
+	}
+	c := *a
+	c.db = a.db.WithContext(ctx)
+	return &c
+}
+
+func (a *Adapter) Migrate(entities []any) error {
+	for _, entity := range entities {
+		if err := a.db.AutoMigrate(entity); err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+	}
+	return nil
+}
+
+func (a *Adapter) Create(entity any) error {
+	return a.db.Create(entity).Error
+}
+
+func (a *Adapter) Update(entity any) error {
+	return a.db.Save(entity).Error
+}
+
+func (a *Adapter) Delete(id string, entity any) error {
+	return a.db.Delete(entity, "id = ?", id).Error
+}
+
+func (a *Adapter) FindAll(entity any, filters map[string]any, pagination db.Pagination, sort []db.Sort) (any, error) {
+	entityType := reflect.TypeOf(entity)
+	if entityType.Kind() == reflect.Ptr {
+		entityType = entityType.Elem()
+	}
+
+	sliceType := reflect.SliceOf(entityType)
+
+	resultValue := reflect.New(sliceType) // *([]Entity)
+
+	column, err := a.columns(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := a.db.Model(entity)
+
+	for key, val := range filters {
+		col, err := column(key)
+		if err != nil {
+			return nil, err
+		}
+		tx = tx.Where(clause.Eq{Column: col, Value: val})
+	}
+
+	for _, s := range sort {
+		col, err := column(s.Field)
+		if err != nil {
+			return nil, err
+		}
+		tx = tx.Order(clause.OrderByColumn{
+			Column: col,
+			Desc:   strings.EqualFold(s.Direction, "desc"),
+		})
+	}
+
+	if pagination.Limit > 0 {
+		tx = tx.Limit(pagination.Limit)
+	}
+
+	if pagination.Offset > 0 {
+		tx = tx.Offset(pagination.Offset)
+	}
+
+	if err := tx.Find(resultValue.Interface()).Error; err != nil {
+		return nil, err
+	}
+
+	result := resultValue.Elem().Interface()
+
+	return result, nil
+}
+
+func (a *Adapter) UpdateWhere(entity any, filters map[string]any, values map[string]any) (int64, error) {
+	column, err := a.columns(entity)
+	if err != nil {
+		return 0, err
+	}
+
+	tx := a.db.Model(entity)
+	for key, val := range filters {
+		col, err := column(key)
+		if err != nil {
+			return 0, err
+		}
+		tx = tx.Where(clause.Eq{Column: col, Value: val})
+	}
+
+	set := make(map[string]any, len(values))
+	for key, val := range values {
+		col, err := column(key)
+		if err != nil {
+			return 0, err
+		}
+		set[col.Name] = val
+	}
+
+	result := tx.Updates(set)
+	return result.RowsAffected, result.Error
+}
+
+// columns returns a lookup of entity's columns. Filter and sort keys come
+// from query parameters, so only columns of the entity's schema are
+// accepted, and they are quoted by the dialect instead of being
+// interpolated into the SQL.
+func (a *Adapter) columns(entity any) (func(key string) (clause.Column, error), error) {
+	stmt := &gorm.Statement{DB: a.db}
+	if err := stmt.Parse(entity); err != nil {
+		return nil, err
+	}
+	return func(key string) (clause.Column, error) {
+		field, ok := stmt.Schema.FieldsByDBName[key]
+		if !ok {
+			return clause.Column{}, &db.FieldError{Field: key}
+		}
+		return clause.Column{Table: stmt.Schema.Table, Name: field.DBName}, nil
+	}, nil
+}
+
+func (a *Adapter) FindByID(id string, entity any) (any, error) {
+	err := a.db.First(entity, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
@@ -1,5 +1,7 @@
 package db
 
+import "context"
+
 type Pagination struct {
 	Limit  int
 	Offset int
@@ -13,11 +15,19 @@ type Sort struct {
 type DBAdapter interface {
 	Init() error
 	Migrate(entities []any) error
+	Close() error
+	Ping(ctx context.Context) error
 
 	Create(entity any) error
 	Update(entity any) error
 	Delete(id string, entity any) error
 
+	// UpdateWhere sets values on the records of entity's type that match
+	// every filter, a nil filter value matching a null field, and returns
+	// how many it updated. Each record is updated atomically, so a filter on
+	// the old value lets only one of several concurrent callers succeed.
+	UpdateWhere(entity any, filters map[string]any, values map[string]any) (int64, error)
+
 	FindAll(entity any, filters map[string]any, pagination Pagination, sort []Sort) (any, error)
 	FindByID(id string, entity any) (any, error)
 }
@@ -47,6 +47,33 @@ func (m *MongoAdapter) Init() error {
 	return nil
 }
 
+// Close disconnects the client, waiting up to 10 seconds for in-progress
+// operations.
+func (m *MongoAdapter) Close() error {
+	if m.client == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return m.client.Disconnect(ctx)
+}
+
+// Ping checks that the primary is reachable.
+func (m *MongoAdapter) Ping(ctx context.Context) error {
+	if m.client == nil {
+		return errors.New("mongodb: not initialized")
+	}
+	return m.client.Ping(ctx, nil)
+}
+
+// WithContext returns a copy of the adapter whose operations use ctx.
+func (m *MongoAdapter) WithContext(ctx context.Context) db.DBAdapter {
+	c := *m
+	c.ctx = ctx
+	return &c
+}
+
 func (m *MongoAdapter) Migrate(entities []any) error {
 	return nil
 }
@@ -98,6 +125,19 @@ func (m *MongoAdapter) Delete(id string, entity any) error {
 	return err
 }
 
+func (m *MongoAdapter) UpdateWhere(entity any, filters map[string]any, values map[string]any) (int64, error) {
+	filter := bson.M{}
+	for key, val := range filters {
+		filter[key] = val
+	}
+
+	res, err := m.collectionFor(entity).UpdateMany(m.ctx, filter, bson.M{"$set": values})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
 func (m *MongoAdapter) FindAll(entity any, filters map[string]any, pagination db.Pagination, sort []db.Sort) (any, error) {
 	entityType := reflect.TypeOf(entity)
 	if entityType.Kind() == reflect.Ptr {
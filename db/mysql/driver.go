@@ -0,0 +1,24 @@
+// Package mysql is a db.DBAdapter for MySQL and MariaDB.
+package mysql
+
+import (
+	"github.com/Lumicrate/gompose/db/gormdb"
+	sqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+)
+
+type MySQLAdapter struct {
+	*gormdb.Adapter
+}
+
+// New connects with a go-sql-driver DSN, e.g.
+// "user:password@tcp(localhost:3306)/app". parseTime is always enabled, so
+// that time.Time fields can be scanned.
+func New(dsn string) *MySQLAdapter {
+	// An invalid DSN is left as is; Init reports it.
+	if cfg, err := sqldriver.ParseDSN(dsn); err == nil {
+		cfg.ParseTime = true
+		dsn = cfg.FormatDSN()
+	}
+	return &MySQLAdapter{Adapter: gormdb.New("mysql", mysql.Open(dsn))}
+}
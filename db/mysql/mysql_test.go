@@ -0,0 +1,43 @@
+package mysql_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Lumicrate/gompose/auth"
+	"github.com/Lumicrate/gompose/auth/jwt"
+	"github.com/Lumicrate/gompose/auth/session"
+	"github.com/Lumicrate/gompose/db/dbtest"
+	"github.com/Lumicrate/gompose/db/mysql"
+)
+
+// The tests need a disposable database, e.g.
+// GOMPOSE_MYSQL_DSN="root:secret@tcp(localhost:3306)/gompose_test".
+func dsn(t *testing.T) string {
+	dsn := os.Getenv("GOMPOSE_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("GOMPOSE_MYSQL_DSN is not set")
+	}
+	return dsn
+}
+
+func TestAdapter(t *testing.T) {
+	if err := dbtest.TestAdapter(mysql.New(dsn(t))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// MySQL cannot index TEXT columns, so every indexed string of the auth
+// models needs a size.
+func TestMigrateAuthModels(t *testing.T) {
+	adapter := mysql.New(dsn(t))
+	if err := adapter.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer adapter.Close()
+
+	models := []any{&auth.UserModel{}, &jwt.ActionToken{}, &jwt.TwoFactor{}, &session.Session{}}
+	if err := adapter.Migrate(models); err != nil {
+		t.Fatal(err)
+	}
+}
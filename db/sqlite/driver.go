@@ -0,0 +1,41 @@
+// Package sqlite is a db.DBAdapter for SQLite, on a pure-Go driver that
+// needs no cgo. It is meant for local development and hermetic tests.
+package sqlite
+
+import (
+	"github.com/Lumicrate/gompose/db/gormdb"
+	"github.com/glebarez/sqlite"
+)
+
+type SQLiteAdapter struct {
+	*gormdb.Adapter
+}
+
+// New opens the database at dsn, a file path or a "file:" URI.
+func New(dsn string) *SQLiteAdapter {
+	return &SQLiteAdapter{Adapter: gormdb.New("sqlite", sqlite.Open(dsn))}
+}
+
+// NewMemory returns an adapter with its own in-memory database, which is
+// dropped when the adapter is closed.
+func NewMemory() *SQLiteAdapter {
+	return New(":memory:")
+}
+
+// Init opens the database on a single connection: SQLite allows one writer
+// at a time, and an in-memory database only lives as long as its
+// connection.
+func (s *SQLiteAdapter) Init() error {
+	if err := s.Adapter.Init(); err != nil {
+		return err
+	}
+
+	sqlDB, err := s.DB().DB()
+	if err != nil {
+		return err
+	}
+	sqlDB.SetMaxOpenConns(1)
+	sqlDB.SetConnMaxLifetime(0)
+	sqlDB.SetConnMaxIdleTime(0)
+	return nil
+}
@@ -0,0 +1,21 @@
+package sqlite_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Lumicrate/gompose/db/dbtest"
+	"github.com/Lumicrate/gompose/db/sqlite"
+)
+
+func TestAdapter(t *testing.T) {
+	if err := dbtest.TestAdapter(sqlite.NewMemory()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileAdapter(t *testing.T) {
+	if err := dbtest.TestAdapter(sqlite.New(filepath.Join(t.TempDir(), "gompose.db"))); err != nil {
+		t.Fatal(err)
+	}
+}
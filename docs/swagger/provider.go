@@ -2,18 +2,28 @@ package swagger
 
 import (
 	"fmt"
+	"github.com/Lumicrate/gompose/auth"
 	"github.com/Lumicrate/gompose/http"
 	"github.com/getkin/kin-openapi/openapi3"
 	"reflect"
 	"strings"
 )
 
-type SwaggerProvider struct{}
+type SwaggerProvider struct {
+	authProvider auth.AuthProvider
+}
 
 func NewSwaggerProvider() *SwaggerProvider {
 	return &SwaggerProvider{}
 }
 
+// SetAuthProvider lets the generated document describe the security schemes
+// of the given provider instead of the default JWT bearer scheme.
+func (s *SwaggerProvider) SetAuthProvider(provider auth.AuthProvider) *SwaggerProvider {
+	s.authProvider = provider
+	return s
+}
+
 func (s *SwaggerProvider) RegisterRoutes(engine http.HTTPEngine) {
 	doc := s.Generate(engine)
 
@@ -37,6 +47,8 @@ func (s *SwaggerProvider) Generate(engine http.HTTPEngine) *openapi3.T {
 		Paths:   &openapi3.Paths{},
 	}
 
+	schemes, requirements := s.security()
+
 	for _, r := range engine.Routes() {
 		path := r.Path
 		// Convert :id → {id} etc
@@ -153,28 +165,61 @@ func (s *SwaggerProvider) Generate(engine http.HTTPEngine) *openapi3.T {
 		}
 
 		if r.Protected {
-			operation.Security = &openapi3.SecurityRequirements{
-				{"BearerAuth": {}},
-			}
+			operation.Security = &requirements
 		}
 
 	}
 
 	doc.Components = &openapi3.Components{
-		SecuritySchemes: openapi3.SecuritySchemes{
-			"BearerAuth": &openapi3.SecuritySchemeRef{
-				Value: &openapi3.SecurityScheme{
-					Type:         "http",
-					Scheme:       "bearer",
-					BearerFormat: "JWT",
-				},
-			},
-		},
+		SecuritySchemes: schemes,
 	}
 
 	return doc
 }
 
+// security returns the security schemes and the alternative requirements of
+// the configured auth provider, falling back to a JWT bearer scheme.
+func (s *SwaggerProvider) security() (openapi3.SecuritySchemes, openapi3.SecurityRequirements) {
+	describer, ok := s.authProvider.(auth.SecurityDescriber)
+	if !ok {
+		bearer := &openapi3.SecuritySchemeRef{
+			Value: &openapi3.SecurityScheme{
+				Type:         "http",
+				Scheme:       "bearer",
+				BearerFormat: "JWT",
+			},
+		}
+		return openapi3.SecuritySchemes{"BearerAuth": bearer},
+			openapi3.SecurityRequirements{{"BearerAuth": {}}}
+	}
+
+	schemes := openapi3.SecuritySchemes{}
+	for _, sc := range describer.SecuritySchemes() {
+		schemes[sc.Name] = &openapi3.SecuritySchemeRef{
+			Value: &openapi3.SecurityScheme{
+				Type:         sc.Type,
+				Scheme:       sc.Scheme,
+				BearerFormat: sc.BearerFormat,
+				In:           sc.In,
+				Name:         sc.ParamName,
+			},
+		}
+	}
+
+	// Each alternative becomes its own requirement object, which OpenAPI
+	// treats as "any of"; the schemes inside one object are "all of".
+	requirements := openapi3.SecurityRequirements{}
+	for _, alternative := range describer.SecurityRequirements() {
+		req := openapi3.SecurityRequirement{}
+		for _, name := range alternative {
+			req[name] = []string{}
+		}
+		requirements = append(requirements, req)
+	}
+
+	return schemes, requirements
+}
+
 // helper to create string pointer
 func ptrString(s string) *string {
 	return &s
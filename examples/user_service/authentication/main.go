@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"github.com/Lumicrate/gompose/auth/jwt"
 	"github.com/Lumicrate/gompose/core"
 	"github.com/Lumicrate/gompose/crud"
 	"github.com/Lumicrate/gompose/db/postgres"
 	"github.com/Lumicrate/gompose/http/gin"
+	"log"
 	"strconv"
 	"time"
 )
@@ -54,5 +56,7 @@ func main() {
 		UseHTTP(httpEngine).
 		UseAuth(authProvider).
 		UseSwagger()
-	app.Run()
+	if err := app.Run(context.Background()); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"github.com/Lumicrate/gompose/core"
 	"github.com/Lumicrate/gompose/db/mongodb"
@@ -47,11 +48,13 @@ func main() {
 	app := core.NewApp().
 		AddEntity(User{}).
 		UseDB(dbAdapter).
-		RegisterMiddleware(middlewares.LoggingMiddleware()).
-		RegisterMiddleware(middlewares.RateLimitMiddleware(1 * time.Second)).
+		UseAccessLog().
+		RegisterMiddleware(middlewares.RateLimit(middlewares.RateLimitConfig{Requests: 10, Period: time.Second})).
 		UseHTTP(httpEngine)
 
 	// Run the app
 	log.Println("Starting service on :8080")
-	app.Run()
+	if err := app.Run(context.Background()); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -1,11 +1,12 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"github.com/Lumicrate/gompose/core"
 	"github.com/Lumicrate/gompose/db/postgres"
-	"github.com/Lumicrate/gompose/http"
 	"github.com/Lumicrate/gompose/http/gin"
+	"github.com/Lumicrate/gompose/http/middlewares"
 	"log"
 	"strings"
 )
@@ -35,24 +36,6 @@ func (u *User) AfterDelete() error {
 	return nil
 }
 
-// Make you own custom middlewares
-func CORSMiddleware() http.MiddlewareFunc {
-	return func(next http.HandlerFunc) http.HandlerFunc {
-		return func(ctx http.Context) {
-			ctx.SetHeader("Access-Control-Allow-Origin", "*")
-			ctx.SetHeader("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			ctx.SetHeader("Access-Control-Allow-Headers", "Authorization, Content-Type")
-
-			if ctx.Method() == "OPTIONS" {
-				ctx.SetStatus(204)
-				ctx.Abort()
-				return
-			}
-			ctx.Next()
-		}
-	}
-}
-
 func main() {
 	// Configure Postgres DSN
 	dsn := "host=localhost user=username password=password dbname=my_db port=5432 sslmode=disable"
@@ -69,9 +52,12 @@ func main() {
 		AddEntity(Office{}).
 		UseDB(dbAdapter).
 		UseHTTP(httpEngine).
+		RegisterMiddleware(middlewares.CORS(middlewares.DefaultCORSConfig())).
 		UseSwagger()
 
 	// Run the app
 	log.Println("Starting service on :8080")
-	app.Run()
+	if err := app.Run(context.Background()); err != nil {
+		log.Fatal(err)
+	}
 }
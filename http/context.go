@@ -4,6 +4,10 @@ import (
 	"net/http"
 )
 
+// CtxRequestID is the context key of the request ID set by
+// middlewares.RequestID.
+const CtxRequestID = "request_id"
+
 type Context interface {
 	JSON(code int, obj any)
 	Bind(obj any) error
@@ -12,19 +16,24 @@ type Context interface {
 	QueryParams() map[string][]string
 	BindJSON(obj any) error
 	SetHeader(key, value string)
+	AddHeader(key, value string) // adds a value instead of replacing it, e.g. for Set-Cookie
 	Method() string
 	Path() string
+	Route() string // matched route template, e.g. "/users/:id"; empty if no route matched
 	SetStatus(code int)
 	Status() int
+	BytesWritten() int
 	RemoteIP() string
 	Header(header string) string
 	Body(string)
 
 	Abort()
+	// Deprecated: middlewares call next(ctx) to run the rest of the chain.
 	Next()
 
 	Set(key string, value any)
 	Get(key string) any
 
 	Request() *http.Request
+	SetRequest(r *http.Request) // replaces the request, e.g. to wrap its body or context
 }
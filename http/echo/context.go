@@ -0,0 +1,123 @@
+package echoadapter
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// EchoContext implements http.Context on top of echo.Context. One instance
+// serves the whole middleware chain of a request.
+type EchoContext struct {
+	ctx   echo.Context
+	route string
+}
+
+func (e *EchoContext) JSON(code int, obj any) {
+	if code == http.StatusNoContent || code == http.StatusNotModified {
+		e.ctx.Response().WriteHeader(code)
+		return
+	}
+	_ = e.ctx.JSON(code, obj)
+}
+
+func (e *EchoContext) Bind(obj any) error {
+	return e.BindJSON(obj)
+}
+
+func (e *EchoContext) BindJSON(obj any) error {
+	return json.NewDecoder(e.ctx.Request().Body).Decode(obj)
+}
+
+func (e *EchoContext) Param(key string) string {
+	return e.ctx.Param(key)
+}
+
+func (e *EchoContext) Query(key string) string {
+	return e.ctx.QueryParam(key)
+}
+
+func (e *EchoContext) QueryParams() map[string][]string {
+	return e.ctx.QueryParams()
+}
+
+func (e *EchoContext) SetHeader(key, value string) {
+	e.ctx.Response().Header().Set(key, value)
+}
+
+func (e *EchoContext) AddHeader(key, value string) {
+	e.ctx.Response().Header().Add(key, value)
+}
+
+func (e *EchoContext) Method() string {
+	return e.ctx.Request().Method
+}
+
+func (e *EchoContext) Path() string {
+	return e.ctx.Request().URL.Path
+}
+
+func (e *EchoContext) Route() string {
+	return e.route
+}
+
+// SetStatus sets the status that is sent if nothing else is written.
+func (e *EchoContext) SetStatus(code int) {
+	e.ctx.Response().Status = code
+}
+
+func (e *EchoContext) Status() int {
+	return e.ctx.Response().Status
+}
+
+func (e *EchoContext) BytesWritten() int {
+	return int(e.ctx.Response().Size)
+}
+
+func (e *EchoContext) RemoteIP() string {
+	return e.ctx.RealIP()
+}
+
+func (e *EchoContext) Header(header string) string {
+	return e.ctx.Request().Header.Get(header)
+}
+
+func (e *EchoContext) Body(content string) {
+	_, _ = e.ctx.Response().Write([]byte(content))
+}
+
+// Abort is a no-op; the chain stops because the middleware does not call
+// next.
+func (e *EchoContext) Abort() {}
+
+// Next is a no-op; middlewares call next(ctx).
+func (e *EchoContext) Next() {}
+
+func (e *EchoContext) Set(key string, value any) {
+	e.ctx.Set(key, value)
+}
+
+func (e *EchoContext) Get(key string) any {
+	return e.ctx.Get(key)
+}
+
+func (e *EchoContext) Request() *http.Request {
+	return e.ctx.Request()
+}
+
+func (e *EchoContext) SetRequest(r *http.Request) {
+	e.ctx.SetRequest(r)
+}
+
+// Echo returns the underlying echo.Context.
+func (e *EchoContext) Echo() echo.Context {
+	return e.ctx
+}
+
+// finish sends a status set with SetStatus when the handler wrote nothing.
+func (e *EchoContext) finish() {
+	if res := e.ctx.Response(); !res.Committed {
+		res.WriteHeader(res.Status)
+	}
+}
@@ -0,0 +1,99 @@
+package echoadapter
+
+import (
+	"context"
+	"errors"
+	nethttp "net/http"
+	"strings"
+
+	"github.com/Lumicrate/gompose/http"
+	"github.com/labstack/echo/v4"
+)
+
+type EchoEngine struct {
+	*http.Router
+
+	echo *echo.Echo
+	port int
+
+	config http.ServerConfig
+}
+
+func New(port int) *EchoEngine {
+	e := &EchoEngine{
+		echo: echo.New(),
+		port: port,
+	}
+	e.Router = http.NewRouter(e.mount)
+	e.echo.HideBanner = true
+	e.echo.HidePort = true
+
+	// Unmatched requests still run the global middlewares before the 404.
+	e.echo.RouteNotFound("/*", func(c echo.Context) error {
+		serve(e.NotFound(), "", c)
+		return nil
+	})
+
+	return e
+}
+
+func (e *EchoEngine) Init(_ int) error {
+	return nil
+}
+
+// Handler returns the underlying echo instance as an http.Handler.
+func (e *EchoEngine) Handler() nethttp.Handler {
+	return e.echo
+}
+
+// Echo returns the underlying echo instance, e.g. to add echo middleware.
+func (e *EchoEngine) Echo() *echo.Echo {
+	return e.echo
+}
+
+// SetServerConfig sets the bind address, TLS, timeouts and HTTP/2 options
+// used by Start. It must be called before Start.
+func (e *EchoEngine) SetServerConfig(cfg http.ServerConfig) *EchoEngine {
+	e.config = cfg
+	return e
+}
+
+func (e *EchoEngine) Start() error {
+	server := e.echo.Server
+	server.Addr = e.config.Address(e.port)
+	if err := e.config.Apply(server); err != nil {
+		return err
+	}
+
+	err := e.echo.StartServer(server)
+	if errors.Is(err, nethttp.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func (e *EchoEngine) Shutdown(ctx context.Context) error {
+	return e.echo.Shutdown(ctx)
+}
+
+// mount registers a chain built by the router with echo.
+func (e *EchoEngine) mount(method, path string, handler http.HandlerFunc) {
+	e.echo.Add(method, echoPattern(path), func(c echo.Context) error {
+		serve(handler, path, c)
+		return nil
+	})
+}
+
+func serve(chain http.HandlerFunc, route string, c echo.Context) {
+	ctx := &EchoContext{ctx: c, route: route}
+	chain(ctx)
+	ctx.finish()
+}
+
+// echoPattern converts a named "*path" wildcard to echo's "*".
+func echoPattern(path string) string {
+	if i := strings.LastIndex(path, "/*"); i >= 0 {
+		return path[:i] + "/*"
+	}
+	return path
+}
@@ -0,0 +1,15 @@
+package echoadapter_test
+
+import (
+	"testing"
+
+	"github.com/Lumicrate/gompose/http"
+	echoadapter "github.com/Lumicrate/gompose/http/echo"
+	"github.com/Lumicrate/gompose/http/enginetest"
+)
+
+func TestEngine(t *testing.T) {
+	if err := enginetest.TestEngine(func() http.HTTPEngine { return echoadapter.New(0) }); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,357 @@
+// Package enginetest checks that an http.HTTPEngine adapter follows the
+// middleware and context contract documented on http.MiddlewareFunc.
+//
+// Adapters call it from their own tests:
+//
+//	func TestEngine(t *testing.T) {
+//		if err := enginetest.TestEngine(func() http.HTTPEngine { return myadapter.New(0) }); err != nil {
+//			t.Fatal(err)
+//		}
+//	}
+package enginetest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/Lumicrate/gompose/http"
+)
+
+type check struct {
+	name string
+	run  func(newEngine func() http.HTTPEngine) error
+}
+
+var checks = []check{
+	{"middleware order", checkOrder},
+	{"single context", checkSingleContext},
+	{"abort", checkAbort},
+	{"status after next", checkStatusAfterNext},
+	{"set request", checkSetRequest},
+	{"params and route", checkParams},
+	{"groups", checkGroups},
+	{"options", checkOptions},
+	{"add header", checkAddHeader},
+	{"not found", checkNotFound},
+}
+
+// TestEngine runs every check on fresh engines from newEngine and returns
+// the failures joined into one error, or nil.
+func TestEngine(newEngine func() http.HTTPEngine) error {
+	var errs []error
+	for _, c := range checks {
+		if err := c.run(newEngine); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type response struct {
+	code   int
+	header nethttp.Header
+	body   string
+}
+
+func serve(e http.HTTPEngine, method, target string, body string) response {
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, r)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	e.Handler().ServeHTTP(rec, req)
+	return response{code: rec.Code, header: rec.Header(), body: rec.Body.String()}
+}
+
+// trace records the order middlewares and the handler run in.
+func trace(log *[]string, name string) http.MiddlewareFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(ctx http.Context) {
+			*log = append(*log, name+">")
+			next(ctx)
+			*log = append(*log, "<"+name)
+		}
+	}
+}
+
+func checkOrder(newEngine func() http.HTTPEngine) error {
+	var log []string
+	e := newEngine()
+	e.Use(trace(&log, "a"))
+	e.Use(trace(&log, "b"))
+	e.RegisterRoute("GET", "/order", func(ctx http.Context) {
+		log = append(log, "handler")
+		ctx.JSON(200, map[string]string{})
+	}, nil, false)
+
+	serve(e, "GET", "/order", "")
+
+	want := "a> b> handler <b <a"
+	if got := strings.Join(log, " "); got != want {
+		return fmt.Errorf("ran %q, want %q", got, want)
+	}
+	return nil
+}
+
+func checkSingleContext(newEngine func() http.HTTPEngine) error {
+	var errs []error
+	e := newEngine()
+	e.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(ctx http.Context) {
+			ctx.Set("self", ctx)
+			ctx.Set("from_middleware", "m")
+			next(ctx)
+			if ctx.Get("from_handler") != "h" {
+				errs = append(errs, errors.New("value set by the handler is not visible to the middleware after next"))
+			}
+		}
+	})
+	e.RegisterRoute("GET", "/context", func(ctx http.Context) {
+		if ctx.Get("self") != ctx {
+			errs = append(errs, errors.New("handler got a different Context instance than the middleware"))
+		}
+		if ctx.Get("from_middleware") != "m" {
+			errs = append(errs, errors.New("value set by the middleware is not visible to the handler"))
+		}
+		ctx.Set("from_handler", "h")
+		ctx.JSON(200, map[string]string{})
+	}, nil, false)
+
+	serve(e, "GET", "/context", "")
+	return errors.Join(errs...)
+}
+
+func checkAbort(newEngine func() http.HTTPEngine) error {
+	called := false
+	e := newEngine()
+	e.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(ctx http.Context) {
+			ctx.JSON(403, map[string]string{"error": "forbidden"})
+			ctx.Abort()
+		}
+	})
+	e.RegisterRoute("GET", "/abort", func(ctx http.Context) {
+		called = true
+		ctx.JSON(200, map[string]string{})
+	}, nil, false)
+
+	res := serve(e, "GET", "/abort", "")
+	if called {
+		return errors.New("handler ran although the middleware did not call next")
+	}
+	if res.code != 403 {
+		return fmt.Errorf("status %d, want 403", res.code)
+	}
+	return nil
+}
+
+func checkStatusAfterNext(newEngine func() http.HTTPEngine) error {
+	var status, written int
+	e := newEngine()
+	e.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(ctx http.Context) {
+			next(ctx)
+			status = ctx.Status()
+			written = ctx.BytesWritten()
+		}
+	})
+	e.RegisterRoute("POST", "/status", func(ctx http.Context) {
+		ctx.JSON(201, map[string]string{"ok": "yes"})
+	}, nil, false)
+
+	res := serve(e, "POST", "/status", "")
+	if status != 201 {
+		return fmt.Errorf("Status() after next is %d, want 201", status)
+	}
+	if written != len(res.body) {
+		return fmt.Errorf("BytesWritten() after next is %d, want %d", written, len(res.body))
+	}
+	return nil
+}
+
+func checkSetRequest(newEngine func() http.HTTPEngine) error {
+	var got string
+	e := newEngine()
+	e.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(ctx http.Context) {
+			r := ctx.Request().Clone(ctx.Request().Context())
+			r.Header.Set("X-Replaced", "yes")
+			ctx.SetRequest(r)
+			next(ctx)
+		}
+	})
+	e.RegisterRoute("GET", "/request", func(ctx http.Context) {
+		got = ctx.Header("X-Replaced")
+		ctx.JSON(200, map[string]string{})
+	}, nil, false)
+
+	serve(e, "GET", "/request", "")
+	if got != "yes" {
+		return errors.New("request replaced with SetRequest is not seen by the handler")
+	}
+	return nil
+}
+
+func checkParams(newEngine func() http.HTTPEngine) error {
+	var param, query, route, path string
+	var bound struct {
+		Name string `json:"name"`
+	}
+	e := newEngine()
+	e.RegisterRoute("PUT", "/items/:id", func(ctx http.Context) {
+		param, query, route, path = ctx.Param("id"), ctx.Query("q"), ctx.Route(), ctx.Path()
+		if err := ctx.Bind(&bound); err != nil {
+			ctx.JSON(400, map[string]string{"error": err.Error()})
+			return
+		}
+		ctx.JSON(200, bound)
+	}, nil, false)
+
+	res := serve(e, "PUT", "/items/42?q=x", `{"name":"n"}`)
+	switch {
+	case res.code != 200:
+		return fmt.Errorf("status %d, want 200: %s", res.code, res.body)
+	case param != "42":
+		return fmt.Errorf("Param(id) = %q, want 42", param)
+	case query != "x":
+		return fmt.Errorf("Query(q) = %q, want x", query)
+	case route != "/items/:id":
+		return fmt.Errorf("Route() = %q, want /items/:id", route)
+	case path != "/items/42":
+		return fmt.Errorf("Path() = %q, want /items/42", path)
+	case bound.Name != "n":
+		return fmt.Errorf("Bind decoded %q, want n", bound.Name)
+	}
+	return nil
+}
+
+func checkGroups(newEngine func() http.HTTPEngine) error {
+	var log []string
+	e := newEngine()
+	e.Use(trace(&log, "global"))
+	admin := e.Group("/admin")
+	admin.Use(trace(&log, "admin"))
+	nested := admin.Group("/v1")
+	nested.Use(trace(&log, "v1"))
+
+	var route string
+	handler := func(ctx http.Context) {
+		route = ctx.Route()
+		ctx.JSON(200, map[string]string{})
+	}
+	nested.RegisterRoute("GET", "/users/:id", handler, nil, false)
+	e.RegisterRoute("GET", "/public", handler, nil, false)
+
+	var errs []error
+	if res := serve(e, "GET", "/admin/v1/users/1", ""); res.code != 200 {
+		errs = append(errs, fmt.Errorf("group route: status %d, want 200", res.code))
+	}
+	if want := "global> admin> v1> <v1 <admin <global"; strings.Join(log, " ") != want {
+		errs = append(errs, fmt.Errorf("group route ran %q, want %q", strings.Join(log, " "), want))
+	}
+	if route != "/admin/v1/users/:id" {
+		errs = append(errs, fmt.Errorf("group route: Route() = %q, want /admin/v1/users/:id", route))
+	}
+
+	log = nil
+	serve(e, "GET", "/public", "")
+	if want := "global> <global"; strings.Join(log, " ") != want {
+		errs = append(errs, fmt.Errorf("root route ran %q, want %q", strings.Join(log, " "), want))
+	}
+
+	found := false
+	for _, r := range e.Routes() {
+		if r.Method == "GET" && r.Path == "/admin/v1/users/:id" {
+			found = true
+		}
+	}
+	if !found {
+		errs = append(errs, errors.New("Routes() does not list the group route with its full path"))
+	}
+
+	return errors.Join(errs...)
+}
+
+func checkOptions(newEngine func() http.HTTPEngine) error {
+	ran := false
+	e := newEngine()
+	e.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(ctx http.Context) {
+			ran = true
+			next(ctx)
+		}
+	})
+	noop := func(ctx http.Context) { ctx.JSON(200, map[string]string{}) }
+	e.RegisterRoute("GET", "/things/:id", noop, nil, false)
+	e.RegisterRoute("DELETE", "/things/:id", noop, nil, false)
+
+	res := serve(e, "OPTIONS", "/things/1", "")
+	switch {
+	case res.code != 204:
+		return fmt.Errorf("status %d, want 204", res.code)
+	case !strings.Contains(res.header.Get("Allow"), "DELETE"):
+		return fmt.Errorf("Allow = %q, want it to list DELETE", res.header.Get("Allow"))
+	case !ran:
+		return errors.New("global middleware did not run")
+	}
+
+	// Middlewares added after the routes still run for OPTIONS.
+	late := false
+	g := e.Group("/admin")
+	g.RegisterRoute("GET", "/stats", noop, nil, false)
+	g.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(ctx http.Context) {
+			late = true
+			next(ctx)
+		}
+	})
+	if res := serve(e, "OPTIONS", "/admin/stats", ""); res.code != 204 || !late {
+		return fmt.Errorf("OPTIONS /admin/stats: status %d, late group middleware ran %v", res.code, late)
+	}
+	return nil
+}
+
+func checkAddHeader(newEngine func() http.HTTPEngine) error {
+	e := newEngine()
+	e.RegisterRoute("GET", "/cookies", func(ctx http.Context) {
+		ctx.AddHeader("Set-Cookie", "a=1")
+		ctx.AddHeader("Set-Cookie", "b=2")
+		ctx.JSON(200, map[string]string{})
+	}, nil, false)
+
+	res := serve(e, "GET", "/cookies", "")
+	cookies := res.header.Values("Set-Cookie")
+	if len(cookies) != 2 {
+		return fmt.Errorf("Set-Cookie = %q, want both cookies", cookies)
+	}
+	return nil
+}
+
+func checkNotFound(newEngine func() http.HTTPEngine) error {
+	ran := false
+	e := newEngine()
+	e.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(ctx http.Context) {
+			ran = true
+			next(ctx)
+		}
+	})
+	e.RegisterRoute("GET", "/exists", func(ctx http.Context) { ctx.JSON(200, map[string]string{}) }, nil, false)
+
+	res := serve(e, "GET", "/missing", "")
+	switch {
+	case res.code != 404:
+		return fmt.Errorf("status %d, want 404", res.code)
+	case !ran:
+		return errors.New("global middleware did not run")
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package fiberadapter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// FiberContext implements http.Context on top of fiber.Ctx. One instance
+// serves the whole middleware chain of a request.
+//
+// Request() converts the fasthttp request to a *net/http.Request on first
+// use. Once a request is set with SetRequest, its headers, body and context
+// are used instead of fiber's.
+type FiberContext struct {
+	ctx   *fiber.Ctx
+	route string
+	req   *http.Request
+}
+
+func (f *FiberContext) JSON(code int, obj any) {
+	if code == http.StatusNoContent || code == http.StatusNotModified {
+		f.ctx.Status(code)
+		return
+	}
+	_ = f.ctx.Status(code).JSON(obj)
+}
+
+func (f *FiberContext) Bind(obj any) error {
+	return f.BindJSON(obj)
+}
+
+func (f *FiberContext) BindJSON(obj any) error {
+	if f.req != nil {
+		return json.NewDecoder(f.req.Body).Decode(obj)
+	}
+
+	body := f.ctx.Body()
+	if len(body) == 0 {
+		return io.EOF
+	}
+	return json.Unmarshal(body, obj)
+}
+
+func (f *FiberContext) Param(key string) string {
+	return f.ctx.Params(key)
+}
+
+func (f *FiberContext) Query(key string) string {
+	return f.ctx.Query(key)
+}
+
+func (f *FiberContext) QueryParams() map[string][]string {
+	params := map[string][]string{}
+	f.ctx.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		params[string(key)] = append(params[string(key)], string(value))
+	})
+	return params
+}
+
+func (f *FiberContext) SetHeader(key, value string) {
+	f.ctx.Set(key, value)
+}
+
+// AddHeader adds a header value. fasthttp keeps one Set-Cookie per cookie
+// name, so cookies with different names are all sent.
+func (f *FiberContext) AddHeader(key, value string) {
+	f.ctx.Response().Header.Add(key, value)
+}
+
+func (f *FiberContext) Method() string {
+	return f.ctx.Method()
+}
+
+func (f *FiberContext) Path() string {
+	return f.ctx.Path()
+}
+
+func (f *FiberContext) Route() string {
+	return f.route
+}
+
+func (f *FiberContext) SetStatus(code int) {
+	f.ctx.Status(code)
+}
+
+func (f *FiberContext) Status() int {
+	return f.ctx.Response().StatusCode()
+}
+
+func (f *FiberContext) BytesWritten() int {
+	return len(f.ctx.Response().Body())
+}
+
+func (f *FiberContext) RemoteIP() string {
+	return f.ctx.IP()
+}
+
+func (f *FiberContext) Header(header string) string {
+	if f.req != nil {
+		return f.req.Header.Get(header)
+	}
+	return f.ctx.Get(header)
+}
+
+func (f *FiberContext) Body(content string) {
+	_, _ = f.ctx.WriteString(content)
+}
+
+// Abort is a no-op; the chain stops because the middleware does not call
+// next.
+func (f *FiberContext) Abort() {}
+
+// Next is a no-op; middlewares call next(ctx).
+func (f *FiberContext) Next() {}
+
+func (f *FiberContext) Set(key string, value any) {
+	f.ctx.Locals(key, value)
+}
+
+func (f *FiberContext) Get(key string) any {
+	return f.ctx.Locals(key)
+}
+
+func (f *FiberContext) Request() *http.Request {
+	if f.req == nil {
+		r := new(http.Request)
+		if err := fasthttpadaptor.ConvertRequest(f.ctx.Context(), r, true); err == nil {
+			f.req = r
+		} else {
+			f.req, _ = http.NewRequest(f.ctx.Method(), f.ctx.OriginalURL(), nil)
+		}
+	}
+	return f.req
+}
+
+func (f *FiberContext) SetRequest(r *http.Request) {
+	f.req = r
+}
+
+// Fiber returns the underlying fiber.Ctx.
+func (f *FiberContext) Fiber() *fiber.Ctx {
+	return f.ctx
+}
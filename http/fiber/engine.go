@@ -0,0 +1,160 @@
+package fiberadapter
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	nethttp "net/http"
+	"strings"
+	"sync"
+
+	"github.com/Lumicrate/gompose/http"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+)
+
+type FiberEngine struct {
+	*http.Router
+
+	app  *fiber.App
+	port int
+
+	config http.ServerConfig
+
+	mu       sync.Mutex
+	stopped  bool
+	listener net.Listener
+}
+
+func New(port int) *FiberEngine {
+	e := &FiberEngine{port: port}
+	e.Router = http.NewRouter(e.mount)
+	e.app = fiber.New(fiber.Config{
+		DisableStartupMessage: true,
+		// Values from fiber.Ctx outlive the handler, e.g. in ctx.Set.
+		Immutable:    true,
+		ErrorHandler: e.errorHandler,
+	})
+
+	return e
+}
+
+// errorHandler runs the global middlewares for unmatched requests before
+// the 404, like the other engines.
+func (e *FiberEngine) errorHandler(c *fiber.Ctx, err error) error {
+	var fe *fiber.Error
+	if !errors.As(err, &fe) || fe.Code != fiber.StatusNotFound {
+		return fiber.DefaultErrorHandler(c, err)
+	}
+
+	e.NotFound()(&FiberContext{ctx: c})
+	return nil
+}
+
+func (e *FiberEngine) Init(_ int) error {
+	return nil
+}
+
+// Handler returns the fiber app as an http.Handler. Requests are converted
+// to fasthttp, so prefer Start in production.
+func (e *FiberEngine) Handler() nethttp.Handler {
+	return adaptor.FiberApp(e.app)
+}
+
+// App returns the underlying fiber app, e.g. to add fiber middleware.
+func (e *FiberEngine) App() *fiber.App {
+	return e.app
+}
+
+// SetServerConfig sets the bind address, TLS and timeouts used by Start.
+// fasthttp has no HTTP/2 support, so H2C is rejected; ReadHeaderTimeout is
+// covered by ReadTimeout and MaxHeaderBytes sets the read buffer size.
+func (e *FiberEngine) SetServerConfig(cfg http.ServerConfig) *FiberEngine {
+	e.config = cfg
+	return e
+}
+
+func (e *FiberEngine) Start() error {
+	if e.config.H2C {
+		return errors.New("fiber: h2c is not supported")
+	}
+	tlsConfig, err := e.config.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	server := e.app.Server()
+	if e.config.ReadTimeout > 0 {
+		server.ReadTimeout = e.config.ReadTimeout
+	}
+	if e.config.WriteTimeout > 0 {
+		server.WriteTimeout = e.config.WriteTimeout
+	}
+	if e.config.IdleTimeout > 0 {
+		server.IdleTimeout = e.config.IdleTimeout
+	}
+	if e.config.MaxHeaderBytes > 0 {
+		server.ReadBufferSize = e.config.MaxHeaderBytes
+	}
+
+	// The stopped check and the listener assignment happen under one lock,
+	// so a concurrent Shutdown either prevents the listen or closes the
+	// listener, even before fiber starts serving on it.
+	e.mu.Lock()
+	if e.stopped {
+		e.mu.Unlock()
+		return nil
+	}
+	ln, err := net.Listen("tcp", e.config.Address(e.port))
+	if err != nil {
+		e.mu.Unlock()
+		return fmt.Errorf("fiber: %w", err)
+	}
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+	e.listener = ln
+	e.mu.Unlock()
+
+	err = e.app.Listener(ln)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stopped {
+		return nil
+	}
+	return err
+}
+
+func (e *FiberEngine) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	e.stopped = true
+	ln := e.listener
+	e.mu.Unlock()
+
+	err := e.app.ShutdownWithContext(ctx)
+	if ln != nil {
+		// Stops a Start that has not reached fiber's Serve yet; otherwise
+		// the listener is already closed and this is a no-op.
+		_ = ln.Close()
+	}
+	return err
+}
+
+// mount registers a chain built by the router with fiber.
+func (e *FiberEngine) mount(method, path string, handler http.HandlerFunc) {
+	e.app.Add(method, fiberPattern(path), func(c *fiber.Ctx) error {
+		handler(&FiberContext{ctx: c, route: path})
+		return nil
+	})
+}
+
+// fiberPattern converts a named "*path" wildcard to fiber's "*".
+func fiberPattern(path string) string {
+	if i := strings.LastIndex(path, "/*"); i >= 0 {
+		return path[:i] + "/*"
+	}
+	return path
+}
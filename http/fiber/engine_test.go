@@ -0,0 +1,15 @@
+package fiberadapter_test
+
+import (
+	"testing"
+
+	"github.com/Lumicrate/gompose/http"
+	"github.com/Lumicrate/gompose/http/enginetest"
+	fiberadapter "github.com/Lumicrate/gompose/http/fiber"
+)
+
+func TestEngine(t *testing.T) {
+	if err := enginetest.TestEngine(func() http.HTTPEngine { return fiberadapter.New(0) }); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -7,8 +7,7 @@ import (
 )
 
 type GinContext struct {
-	ctx    *gin.Context
-	values map[string]any
+	ctx *gin.Context
 }
 
 func (g *GinContext) JSON(code int, obj any) {
@@ -30,6 +29,10 @@ func (g *GinContext) SetHeader(key, value string) {
 	g.ctx.Writer.Header().Set(key, value)
 }
 
+func (g *GinContext) AddHeader(key, value string) {
+	g.ctx.Writer.Header().Add(key, value)
+}
+
 func (g *GinContext) Method() string {
 	return g.ctx.Request.Method
 }
@@ -38,6 +41,17 @@ func (g *GinContext) Path() string {
 	return g.ctx.Request.URL.Path
 }
 
+func (g *GinContext) Route() string {
+	return g.ctx.FullPath()
+}
+
+func (g *GinContext) BytesWritten() int {
+	if size := g.ctx.Writer.Size(); size > 0 {
+		return size
+	}
+	return 0
+}
+
 func (g *GinContext) Status() int {
 	return g.ctx.Writer.Status()
 }
@@ -62,18 +76,15 @@ func (g *GinContext) Header(header string) string {
 	return g.ctx.GetHeader(header)
 }
 
+// Set and Get use gin's per-request keys, so values set by a route's
+// middleware are visible to global middlewares after next returns.
 func (g *GinContext) Set(key string, value any) {
-	if g.values == nil {
-		g.values = make(map[string]any)
-	}
-	g.values[key] = value
+	g.ctx.Set(key, value)
 }
 
 func (g *GinContext) Get(key string) any {
-	if g.values == nil {
-		return nil
-	}
-	return g.values[key]
+	value, _ := g.ctx.Get(key)
+	return value
 }
 
 func (g *GinContext) Body(content string) {
@@ -84,3 +95,7 @@ func (g *GinContext) Body(content string) {
 func (g *GinContext) Request() *http.Request {
 	return g.ctx.Request
 }
+
+func (g *GinContext) SetRequest(r *http.Request) {
+	g.ctx.Request = r
+}
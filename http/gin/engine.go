@@ -1,62 +1,57 @@
 package ginadapter
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	nethttp "net/http"
+	"sync"
+
 	"github.com/Lumicrate/gompose/http"
 	"github.com/gin-gonic/gin"
 )
 
 type GinEngine struct {
+	*http.Router
+
 	engine *gin.Engine
 	port   int
-	routes []http.Route
+
+	config http.ServerConfig
+
+	mu        sync.Mutex
+	server    *nethttp.Server
+	serverErr error
 }
 
 func New(port int) *GinEngine {
-	return &GinEngine{
+	g := &GinEngine{
 		engine: gin.Default(),
 		port:   port,
-		routes: []http.Route{},
 	}
+	g.Router = http.NewRouter(g.mount)
+
+	// Unmatched requests still run the global middlewares, e.g. CORS and
+	// the access log, before the 404.
+	g.engine.NoRoute(func(c *gin.Context) {
+		g.NotFound()(&GinContext{ctx: c})
+	})
+
+	return g
 }
 
 func (g *GinEngine) Init(_ int) error {
 	return nil
 }
 
-func (g *GinEngine) RegisterRoute(method string, path string, handler http.HandlerFunc, entity any, isProtected bool) {
-
-	g.routes = append(g.routes, http.Route{
-		Method:    method,
-		Path:      path,
-		Entity:    entity,
-		Protected: isProtected,
-	})
-
-	ginHandler := func(c *gin.Context) {
-		handler(&GinContext{ctx: c})
-	}
-
-	switch method {
-	case "GET":
-		g.engine.GET(path, ginHandler)
-	case "POST":
-		g.engine.POST(path, ginHandler)
-	case "PUT":
-		g.engine.PUT(path, ginHandler)
-	case "PATCH":
-		g.engine.PATCH(path, ginHandler)
-	case "DELETE":
-		g.engine.DELETE(path, ginHandler)
-	default:
-		panic(fmt.Sprintf("Unsupported method: %s", method))
-	}
+// Handler returns the underlying gin engine as an http.Handler.
+func (g *GinEngine) Handler() nethttp.Handler {
+	return g.engine
 }
 
-func (g *GinEngine) Use(middleware http.MiddlewareFunc) {
-	g.engine.Use(func(c *gin.Context) {
-		final := middleware(func(ctx http.Context) {})
-		final(&GinContext{ctx: c})
+// mount registers a chain built by the router with gin.
+func (g *GinEngine) mount(method, path string, handler http.HandlerFunc) {
+	g.engine.Handle(method, path, func(c *gin.Context) {
+		handler(&GinContext{ctx: c})
 	})
 }
 
@@ -68,10 +63,38 @@ func (g *GinContext) BindJSON(obj any) error {
 	return g.ctx.ShouldBindJSON(obj)
 }
 
+// SetServerConfig sets the bind address, TLS, timeouts and HTTP/2 options
+// used by Start. It must be called before Start.
+func (g *GinEngine) SetServerConfig(cfg http.ServerConfig) *GinEngine {
+	g.config = cfg
+	return g
+}
+
 func (g *GinEngine) Start() error {
-	return g.engine.Run(fmt.Sprintf(":%d", g.port))
+	server, err := g.httpServer()
+	if err != nil {
+		return err
+	}
+
+	err = g.config.Serve(server)
+	if errors.Is(err, nethttp.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func (g *GinEngine) Shutdown(ctx context.Context) error {
+	server, _ := g.httpServer()
+	return server.Shutdown(ctx)
 }
 
-func (g *GinEngine) Routes() []http.Route {
-	return g.routes
+func (g *GinEngine) httpServer() (*nethttp.Server, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.server == nil {
+		g.server = &nethttp.Server{Addr: g.config.Address(g.port), Handler: g.engine}
+		g.serverErr = g.config.Apply(g.server)
+	}
+	return g.server, g.serverErr
 }
@@ -0,0 +1,15 @@
+package ginadapter_test
+
+import (
+	"testing"
+
+	"github.com/Lumicrate/gompose/http"
+	"github.com/Lumicrate/gompose/http/enginetest"
+	ginadapter "github.com/Lumicrate/gompose/http/gin"
+)
+
+func TestEngine(t *testing.T) {
+	if err := enginetest.TestEngine(func() http.HTTPEngine { return ginadapter.New(0) }); err != nil {
+		t.Fatal(err)
+	}
+}
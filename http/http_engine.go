@@ -1,8 +1,34 @@
 package http
 
+import (
+	"context"
+	"net/http"
+)
+
 type HandlerFunc func(ctx Context)
+
+// MiddlewareFunc wraps the rest of the chain. Engines must honour this
+// contract, which enginetest.TestEngine checks:
+//
+//   - Middlewares run in the order they were added: global ones first, then
+//     those of the enclosing groups from the outermost in, then the route's.
+//   - Calling next(ctx) runs the rest of the chain and the handler, and
+//     returns when they are done. Code after next sees the final status.
+//   - Not calling next stops the chain; the middleware writes the response.
+//   - One Context instance flows through the whole chain, so values set
+//     with Set are visible everywhere in the request.
+//   - Use applies to routes registered after it.
+//   - Unmatched requests run the global middlewares before the 404.
 type MiddlewareFunc func(next HandlerFunc) HandlerFunc
 
+// Chain wraps handler in middlewares so that the first one runs first.
+func Chain(middlewares []MiddlewareFunc, handler HandlerFunc) HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
 type Route struct {
 	Method    string
 	Path      string
@@ -10,10 +36,26 @@ type Route struct {
 	Protected bool
 }
 
-type HTTPEngine interface {
-	Init(port int) error
+// RouteGroup registers routes under a shared path prefix. Middleware added
+// with Use applies to the routes registered on the group, and its subgroups,
+// after the call.
+type RouteGroup interface {
 	RegisterRoute(method string, path string, handler HandlerFunc, entity any, isProtected bool)
 	Use(middleware MiddlewareFunc)
+	Group(prefix string) RouteGroup
+}
+
+// HTTPEngine is the root route group. Routes() lists the routes of all
+// groups with their full paths.
+//
+// Start blocks until the server stops and returns nil after Shutdown.
+// Shutdown stops accepting connections and waits for in-flight requests
+// until ctx is done; called before Start, it makes Start return at once.
+type HTTPEngine interface {
+	Init(port int) error
+	RouteGroup
 	Start() error
+	Shutdown(ctx context.Context) error
 	Routes() []Route
+	Handler() http.Handler // the engine as a net/http handler, for mounting and tests
 }
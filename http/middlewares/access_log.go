@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Lumicrate/gompose/auth"
+	"github.com/Lumicrate/gompose/http"
+)
+
+// AccessLog logs one structured record per request with the method, route
+// template, status, latency, response size, user ID and request ID. Server
+// errors are logged at error level and client errors at warn level. A nil
+// logger uses slog.Default().
+//
+// Register it after RequestID so the request ID is included.
+func AccessLog(logger *slog.Logger) http.MiddlewareFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(ctx http.Context) {
+			l := logger
+			if l == nil {
+				l = slog.Default()
+			}
+
+			start := time.Now()
+			next(ctx)
+			latency := time.Since(start)
+
+			status := ctx.Status()
+			level := slog.LevelInfo
+			switch {
+			case status >= 500:
+				level = slog.LevelError
+			case status >= 400:
+				level = slog.LevelWarn
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", ctx.Method()),
+				slog.String("route", ctx.Route()),
+				slog.String("path", ctx.Path()),
+				slog.Int("status", status),
+				slog.Duration("latency", latency),
+				slog.Int("bytes", ctx.BytesWritten()),
+				slog.String("ip", ctx.RemoteIP()),
+			}
+			if id := ctx.Get(auth.CtxUserID); id != nil && id != "" {
+				attrs = append(attrs, slog.String("user_id", fmt.Sprint(id)))
+			}
+			if id := RequestIDFrom(ctx); id != "" {
+				attrs = append(attrs, slog.String("request_id", id))
+			}
+
+			l.LogAttrs(ctx.Request().Context(), level, "request", attrs...)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package middlewares
+
+import (
+	"io"
+	nethttp "net/http"
+
+	"github.com/Lumicrate/gompose/http"
+)
+
+const ctxLimitedBody = "body_limit_body"
+
+// BodyLimit limits request bodies to maxBytes. Reading a larger body, or
+// one whose Content-Length is larger, fails with *net/http.MaxBytesError,
+// and BodyLimit answers 413 if the handler did not respond itself.
+//
+// The limit is checked when the body is read, so a BodyLimit on a route
+// overrides a global one in both directions.
+func BodyLimit(maxBytes int64) http.MiddlewareFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(ctx http.Context) {
+			if body, ok := ctx.Get(ctxLimitedBody).(*limitedBody); ok {
+				body.limit = maxBytes
+				next(ctx)
+				return
+			}
+
+			req := ctx.Request()
+			if req.Body == nil || req.Body == nethttp.NoBody {
+				next(ctx)
+				return
+			}
+
+			body := &limitedBody{body: req.Body, limit: maxBytes, contentLength: req.ContentLength}
+			ctx.Set(ctxLimitedBody, body)
+
+			r := req.WithContext(req.Context())
+			r.Body = body
+			ctx.SetRequest(r)
+
+			next(ctx)
+
+			if body.exceeded && ctx.BytesWritten() == 0 {
+				ctx.JSON(413, map[string]string{"error": "request body too large"})
+				ctx.Abort()
+			}
+		}
+	}
+}
+
+type limitedBody struct {
+	body          io.ReadCloser
+	limit         int64
+	contentLength int64
+	read          int64
+	exceeded      bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded || b.contentLength > b.limit {
+		b.exceeded = true
+		return 0, &nethttp.MaxBytesError{Limit: b.limit}
+	}
+
+	// Read one byte more than allowed to tell a body of exactly the limit
+	// from a larger one.
+	remaining := b.limit - b.read
+	if int64(len(p)) > remaining+1 {
+		p = p[:remaining+1]
+	}
+
+	n, err := b.body.Read(p)
+	if int64(n) <= remaining {
+		b.read += int64(n)
+		return n, err
+	}
+
+	b.read = b.limit
+	b.exceeded = true
+	return int(remaining), &nethttp.MaxBytesError{Limit: b.limit}
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,86 @@
+package middlewares_test
+
+import (
+	"errors"
+	"io"
+	nethttp "net/http"
+	"strings"
+	"testing"
+
+	"github.com/Lumicrate/gompose/http"
+	"github.com/Lumicrate/gompose/http/middlewares"
+)
+
+// readBody answers with the body length, and leaves the answer to BodyLimit
+// when the body is too large, like the crud handlers do.
+func readBody(ctx http.Context) {
+	data, err := io.ReadAll(ctx.Request().Body)
+	var tooLarge *nethttp.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(400, map[string]string{"error": err.Error()})
+		return
+	}
+	ctx.JSON(200, map[string]int{"length": len(data)})
+}
+
+func TestBodyLimit(t *testing.T) {
+	e := newEngine(middlewares.BodyLimit(10))
+	e.RegisterRoute("POST", "/items", readBody, nil, false)
+	e.RegisterRoute("POST", "/uploads", middlewares.BodyLimit(100)(readBody), nil, false)
+
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		chunked bool // no Content-Length
+		code    int
+	}{
+		{"below the limit", "/items", "12345", false, 200},
+		{"exactly the limit", "/items", "1234567890", false, 200},
+		{"over the limit", "/items", "12345678901", false, 413},
+		{"over the limit without Content-Length", "/items", strings.Repeat("x", 50), true, 413},
+		{"exactly the limit without Content-Length", "/items", "1234567890", true, 200},
+		{"route limit raises the global one", "/uploads", strings.Repeat("x", 50), false, 200},
+		{"route limit still applies", "/uploads", strings.Repeat("x", 101), false, 413},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader = strings.NewReader(tt.body)
+			if tt.chunked {
+				body = io.MultiReader(body) // hides the length from httptest
+			}
+			req := request("POST", tt.path, body, nil)
+			if tt.chunked && req.ContentLength != -1 {
+				t.Fatalf("ContentLength = %d, want unknown", req.ContentLength)
+			}
+
+			rec := serve(e, req)
+			if rec.Code != tt.code {
+				t.Fatalf("status %d, want %d; body %s", rec.Code, tt.code, rec.Body)
+			}
+			if tt.code == 413 && !strings.Contains(rec.Body.String(), "request body too large") {
+				t.Errorf("body %s", rec.Body)
+			}
+		})
+	}
+}
+
+// A handler that answers the error itself keeps its response.
+func TestBodyLimitHandlerResponds(t *testing.T) {
+	e := newEngine(middlewares.BodyLimit(4))
+	e.RegisterRoute("POST", "/items", func(ctx http.Context) {
+		var v map[string]any
+		if err := ctx.BindJSON(&v); err != nil {
+			ctx.JSON(400, map[string]string{"error": "invalid input"})
+		}
+	}, nil, false)
+
+	rec := serve(e, request("POST", "/items", strings.NewReader(`{"a":"long"}`), map[string]string{"Content-Type": "application/json"}))
+	if rec.Code != 400 {
+		t.Errorf("status %d, want the handler's 400", rec.Code)
+	}
+}
@@ -0,0 +1,137 @@
+package middlewares
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lumicrate/gompose/http"
+)
+
+type CORSConfig struct {
+	// AllowOrigins lists exact origins ("https://app.example.com"), origins
+	// with wildcards ("https://*.example.com") or "*" for any origin.
+	AllowOrigins []string
+	// AllowOriginPatterns are matched against the whole Origin header, in
+	// lower case.
+	AllowOriginPatterns []*regexp.Regexp
+
+	AllowMethods     []string // defaults to GET, POST, PUT, PATCH, DELETE
+	AllowHeaders     []string // defaults to the headers gompose reads
+	ExposeHeaders    []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowOrigins: []string{"*"},
+		AllowMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowHeaders: []string{"Authorization", "Content-Type", "X-API-Key", "X-CSRF-Token", RequestIDHeader},
+		ExposeHeaders: []string{
+			RequestIDHeader, "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After",
+		},
+		MaxAge: time.Hour,
+	}
+}
+
+// CORS answers preflight requests and adds the Access-Control-* headers to
+// requests from allowed origins. Requests from other origins pass through
+// without CORS headers, so the browser blocks them.
+//
+// It panics if credentials are allowed for any origin, which browsers
+// reject anyway.
+func CORS(cfg CORSConfig) http.MiddlewareFunc {
+	defaults := DefaultCORSConfig()
+	if len(cfg.AllowMethods) == 0 {
+		cfg.AllowMethods = defaults.AllowMethods
+	}
+	if len(cfg.AllowHeaders) == 0 {
+		cfg.AllowHeaders = defaults.AllowHeaders
+	}
+
+	allowAll := false
+	exact := make(map[string]bool)
+	patterns := append([]*regexp.Regexp{}, cfg.AllowOriginPatterns...)
+	for _, o := range cfg.AllowOrigins {
+		o = strings.ToLower(o)
+		switch {
+		case o == "*":
+			allowAll = true
+		case strings.Contains(o, "*"):
+			expr := strings.ReplaceAll(regexp.QuoteMeta(o), `\*`, `[^/]+`)
+			patterns = append(patterns, regexp.MustCompile("^"+expr+"$"))
+		default:
+			exact[o] = true
+		}
+	}
+
+	if allowAll && cfg.AllowCredentials {
+		panic(`cors: AllowCredentials cannot be used with AllowOrigins "*"`)
+	}
+
+	allowed := func(origin string) bool {
+		origin = strings.ToLower(origin)
+		if allowAll || exact[origin] {
+			return true
+		}
+		for _, p := range patterns {
+			if p.MatchString(origin) {
+				return true
+			}
+		}
+		return false
+	}
+
+	methods := strings.Join(cfg.AllowMethods, ", ")
+	headers := strings.Join(cfg.AllowHeaders, ", ")
+	expose := strings.Join(cfg.ExposeHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(ctx http.Context) {
+			origin := ctx.Header("Origin")
+			preflight := http.IsPreflight(ctx)
+
+			if !allowAll {
+				ctx.SetHeader("Vary", "Origin")
+			}
+
+			if origin == "" || !allowed(origin) {
+				if preflight {
+					ctx.SetStatus(204)
+					ctx.Abort()
+					return
+				}
+				next(ctx)
+				return
+			}
+
+			if allowAll {
+				ctx.SetHeader("Access-Control-Allow-Origin", "*")
+			} else {
+				ctx.SetHeader("Access-Control-Allow-Origin", origin)
+			}
+			if cfg.AllowCredentials {
+				ctx.SetHeader("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				if expose != "" {
+					ctx.SetHeader("Access-Control-Expose-Headers", expose)
+				}
+				next(ctx)
+				return
+			}
+
+			ctx.SetHeader("Access-Control-Allow-Methods", methods)
+			ctx.SetHeader("Access-Control-Allow-Headers", headers)
+			if cfg.MaxAge > 0 {
+				ctx.SetHeader("Access-Control-Max-Age", maxAge)
+			}
+			ctx.SetStatus(204)
+			ctx.Abort()
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package middlewares_test
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/Lumicrate/gompose/auth"
+	"github.com/Lumicrate/gompose/http"
+	"github.com/Lumicrate/gompose/http/middlewares"
+	nethttpadapter "github.com/Lumicrate/gompose/http/nethttp"
+)
+
+func TestCORSPreflight(t *testing.T) {
+	cfg := middlewares.DefaultCORSConfig()
+	cfg.AllowOrigins = []string{"https://app.example.com", "https://*.example.org"}
+	cfg.AllowOriginPatterns = []*regexp.Regexp{regexp.MustCompile(`^https://pr-\d+\.preview\.dev$`)}
+	cfg.AllowCredentials = true
+
+	e := newEngine(middlewares.CORS(cfg))
+	e.RegisterRoute("POST", "/items", ok, nil, false)
+	e.RegisterRoute("GET", "/items", ok, nil, false)
+
+	tests := []struct {
+		name, origin string
+		allowed      bool
+	}{
+		{"exact origin", "https://app.example.com", true},
+		{"origin in another case", "https://APP.example.com", true},
+		{"wildcard origin", "https://a.example.org", true},
+		{"wildcard does not cross slashes", "https://a.b/.example.org", false},
+		{"pattern", "https://pr-42.preview.dev", true},
+		{"unknown origin", "https://evil.example", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(e, request("OPTIONS", "/items", nil, map[string]string{
+				"Origin":                        tt.origin,
+				"Access-Control-Request-Method": "POST",
+			}))
+			if rec.Code != 204 {
+				t.Fatalf("status %d, want 204", rec.Code)
+			}
+
+			h := rec.Header()
+			if got := h.Get("Access-Control-Allow-Origin"); (got == tt.origin) != tt.allowed {
+				t.Errorf("Access-Control-Allow-Origin = %q, allowed %v", got, tt.allowed)
+			}
+			if !tt.allowed {
+				return
+			}
+			if got := h.Get("Access-Control-Allow-Methods"); got != "GET, POST, PUT, PATCH, DELETE" {
+				t.Errorf("Access-Control-Allow-Methods = %q", got)
+			}
+			if got := h.Get("Access-Control-Allow-Credentials"); got != "true" {
+				t.Errorf("Access-Control-Allow-Credentials = %q", got)
+			}
+			if got := h.Get("Access-Control-Max-Age"); got != "3600" {
+				t.Errorf("Access-Control-Max-Age = %q", got)
+			}
+			if got := h.Get("Vary"); got != "Origin" {
+				t.Errorf("Vary = %q", got)
+			}
+		})
+	}
+}
+
+func TestCORSSimpleRequest(t *testing.T) {
+	e := newEngine(middlewares.CORS(middlewares.DefaultCORSConfig()))
+	e.RegisterRoute("GET", "/items", ok, nil, false)
+
+	rec := serve(e, request("GET", "/items", nil, map[string]string{"Origin": "https://any.example"}))
+	if rec.Code != 200 {
+		t.Fatalf("status %d, want 200", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+	}
+	if got := rec.Header().Get("Access-Control-Expose-Headers"); got == "" {
+		t.Error("no Access-Control-Expose-Headers")
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); got != "" {
+		t.Errorf("Access-Control-Allow-Methods on a simple request: %q", got)
+	}
+}
+
+// Without a preflight header, OPTIONS reaches the automatic route, which
+// lists the path's methods.
+func TestOptionsRoute(t *testing.T) {
+	e := newEngine(middlewares.CORS(middlewares.DefaultCORSConfig()))
+	e.RegisterRoute("GET", "/items", ok, nil, false)
+	e.RegisterRoute("POST", "/items", ok, nil, false)
+
+	rec := serve(e, request("OPTIONS", "/items", nil, nil))
+	if rec.Code != 204 {
+		t.Fatalf("status %d, want 204", rec.Code)
+	}
+	if got := rec.Header().Get("Allow"); got != "GET, POST, OPTIONS" {
+		t.Errorf("Allow = %q", got)
+	}
+}
+
+type denyAll struct{}
+
+func (denyAll) Name() string                              { return "deny" }
+func (denyAll) Authenticate(http.Context) (string, error) { return "", errors.New("unauthorized") }
+
+// Preflights carry no credentials, so group authentication must let them
+// reach CORS, and CORS added after the routes must still answer them.
+func TestCORSPreflightBehindGroupAuth(t *testing.T) {
+	cors := middlewares.CORS(middlewares.DefaultCORSConfig())
+	deny := auth.Middleware(denyAll{})
+
+	setups := []struct {
+		name  string
+		setup func(e *nethttpadapter.NetHTTPEngine)
+	}{
+		{"global CORS added after the routes", func(e *nethttpadapter.NetHTTPEngine) {
+			admin := e.Group("/admin")
+			admin.Use(deny)
+			admin.RegisterRoute("DELETE", "/users/:id", ok, nil, false)
+			e.Use(cors)
+		}},
+		{"CORS in the group after auth", func(e *nethttpadapter.NetHTTPEngine) {
+			admin := e.Group("/admin")
+			admin.Use(deny)
+			admin.Use(cors)
+			admin.RegisterRoute("DELETE", "/users/:id", ok, nil, false)
+		}},
+	}
+
+	for _, s := range setups {
+		t.Run(s.name, func(t *testing.T) {
+			e := newEngine()
+			s.setup(e)
+
+			rec := serve(e, request("OPTIONS", "/admin/users/1", nil, map[string]string{
+				"Origin":                        "https://app.example.com",
+				"Access-Control-Request-Method": "DELETE",
+			}))
+			if rec.Code != 204 {
+				t.Fatalf("preflight: status %d, want 204", rec.Code)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+				t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+			}
+
+			// The request itself is still authenticated, and so is a
+			// plain OPTIONS request.
+			rec = serve(e, request("DELETE", "/admin/users/1", nil, map[string]string{"Origin": "https://app.example.com"}))
+			if rec.Code != 401 {
+				t.Errorf("DELETE: status %d, want 401", rec.Code)
+			}
+			if rec := serve(e, request("OPTIONS", "/admin/users/1", nil, nil)); rec.Code != 401 {
+				t.Errorf("OPTIONS without preflight headers: status %d, want 401", rec.Code)
+			}
+		})
+	}
+}
+
+func TestCORSCredentialsWithAnyOrigin(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("CORS did not panic for credentials with any origin")
+		}
+	}()
+	cfg := middlewares.DefaultCORSConfig()
+	cfg.AllowCredentials = true
+	middlewares.CORS(cfg)
+}
@@ -6,16 +6,17 @@ import (
 	"time"
 )
 
+// LoggingMiddleware prints one plain-text line per request.
+//
+// Deprecated: use AccessLog, which logs structured records through slog.
 func LoggingMiddleware() http.MiddlewareFunc {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(ctx http.Context) {
 			start := time.Now()
-			ctx.Next()
+			next(ctx)
 			duration := time.Since(start)
 			log.Printf("[%s] %s %s %d %s",
 				ctx.Method(), ctx.Path(), ctx.RemoteIP(), ctx.Status(), duration)
-
-			next(ctx)
 		}
 	}
 }
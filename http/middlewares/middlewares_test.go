@@ -0,0 +1,37 @@
+package middlewares_test
+
+import (
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+
+	"github.com/Lumicrate/gompose/http"
+	nethttpadapter "github.com/Lumicrate/gompose/http/nethttp"
+)
+
+// newEngine returns an engine with the given global middlewares.
+func newEngine(middlewares ...http.MiddlewareFunc) *nethttpadapter.NetHTTPEngine {
+	e := nethttpadapter.New(0)
+	for _, m := range middlewares {
+		e.Use(m)
+	}
+	return e
+}
+
+func ok(ctx http.Context) {
+	ctx.JSON(200, map[string]string{"status": "ok"})
+}
+
+func serve(e http.HTTPEngine, req *nethttp.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	e.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func request(method, target string, body io.Reader, headers map[string]string) *nethttp.Request {
+	req := httptest.NewRequest(method, target, body)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return req
+}
@@ -1,31 +1,237 @@
 package middlewares
 
 import (
-	"github.com/Lumicrate/gompose/http"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/Lumicrate/gompose/auth"
+	"github.com/Lumicrate/gompose/http"
 )
 
-var visitors = make(map[string]time.Time)
-var mu sync.Mutex
+// RateLimitConfig configures a token-bucket limiter: every key gets a bucket
+// of Burst tokens that refills at Requests per Period, and each request
+// takes one token.
+type RateLimitConfig struct {
+	Requests int
+	Period   time.Duration
+	Burst    int // bucket capacity, defaults to Requests
+
+	Key   KeyFunc                 // defaults to KeyByIP
+	Store RateLimitStore          // defaults to a new in-memory store
+	Skip  func(http.Context) bool // requests for which it returns true are not limited
+}
+
+// Limit is the bucket definition passed to a RateLimitStore.
+type Limit struct {
+	Rate  float64 // tokens per second
+	Burst int
+}
+
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	ResetAfter time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next token, when not allowed
+}
+
+// RateLimitStore keeps the buckets. Implementations backed by a shared store
+// such as Redis let several replicas enforce one limit.
+type RateLimitStore interface {
+	Take(key string, limit Limit, now time.Time) (RateLimitResult, error)
+}
+
+// KeyFunc selects the bucket a request is counted against.
+type KeyFunc func(ctx http.Context) string
+
+func KeyByIP(ctx http.Context) string {
+	return "ip:" + ctx.RemoteIP()
+}
+
+// KeyByUserID counts requests per authenticated user and falls back to the
+// client IP for anonymous requests. It needs to run after authentication.
+func KeyByUserID(ctx http.Context) string {
+	if id := ctx.Get(auth.CtxUserID); id != nil && id != "" {
+		return fmt.Sprintf("user:%v", id)
+	}
+	return KeyByIP(ctx)
+}
+
+// KeyByHeader counts requests per value of a header, e.g. an API key, and
+// falls back to the client IP when it is missing.
+func KeyByHeader(name string) KeyFunc {
+	return func(ctx http.Context) string {
+		if v := ctx.Header(name); v != "" {
+			return "header:" + name + ":" + v
+		}
+		return KeyByIP(ctx)
+	}
+}
+
+// KeyByRoute counts requests per route template, so "/users/1" and
+// "/users/2" share a bucket.
+func KeyByRoute(ctx http.Context) string {
+	route := ctx.Route()
+	if route == "" {
+		route = ctx.Path()
+	}
+	return "route:" + ctx.Method() + " " + route
+}
+
+// KeyBy combines several key functions, e.g. KeyBy(KeyByRoute, KeyByIP) for
+// a limit per client and route.
+func KeyBy(keys ...KeyFunc) KeyFunc {
+	return func(ctx http.Context) string {
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = k(ctx)
+		}
+		return strings.Join(parts, "|")
+	}
+}
+
+// RateLimit returns a token-bucket rate limiting middleware. It sets the
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers and
+// answers 429 with Retry-After when the bucket is empty.
+func RateLimit(cfg RateLimitConfig) http.MiddlewareFunc {
+	if cfg.Requests < 1 {
+		cfg.Requests = 1
+	}
+	if cfg.Period <= 0 {
+		cfg.Period = time.Second
+	}
+	if cfg.Burst < 1 {
+		cfg.Burst = cfg.Requests
+	}
+	if cfg.Key == nil {
+		cfg.Key = KeyByIP
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryRateLimitStore()
+	}
+
+	limit := Limit{
+		Rate:  float64(cfg.Requests) / cfg.Period.Seconds(),
+		Burst: cfg.Burst,
+	}
+	policy := fmt.Sprintf("%d;w=%d;burst=%d", cfg.Requests, int(math.Ceil(cfg.Period.Seconds())), cfg.Burst)
 
-func RateLimitMiddleware(limit time.Duration) http.MiddlewareFunc {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(ctx http.Context) {
-			ip := ctx.RemoteIP()
-			mu.Lock()
-			lastRequest, exists := visitors[ip]
-			if exists && time.Since(lastRequest) < limit {
-				mu.Unlock()
+			if cfg.Skip != nil && cfg.Skip(ctx) {
+				next(ctx)
+				return
+			}
+
+			result, err := cfg.Store.Take(cfg.Key(ctx), limit, time.Now())
+			if err != nil {
+				// A broken store should not take the service down with it.
+				next(ctx)
+				return
+			}
+
+			ctx.SetHeader("RateLimit-Policy", policy)
+			ctx.SetHeader("RateLimit-Limit", strconv.Itoa(cfg.Burst))
+			ctx.SetHeader("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			ctx.SetHeader("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+			if !result.Allowed {
+				ctx.SetHeader("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
 				ctx.JSON(429, map[string]string{"error": "rate limit exceeded"})
 				ctx.Abort()
 				return
 			}
-			visitors[ip] = time.Now()
-			mu.Unlock()
-			ctx.Next()
 
 			next(ctx)
 		}
 	}
 }
+
+// RateLimitMiddleware allows one request per interval per IP.
+//
+// Deprecated: use RateLimit, which supports bursts, other keys and stores.
+func RateLimitMiddleware(limit time.Duration) http.MiddlewareFunc {
+	return RateLimit(RateLimitConfig{Requests: 1, Period: limit, Burst: 1})
+}
+
+// MemoryRateLimitStore keeps buckets in process memory. Buckets that have
+// refilled completely are evicted, so idle clients do not use memory.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweptAt time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*bucket)}
+}
+
+func (m *MemoryRateLimitStore) Take(key string, limit Limit, now time.Time) (RateLimitResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now, limit: limit}
+		m.buckets[key] = b
+	}
+
+	b.limit = limit
+	b.refill(now)
+
+	result := RateLimitResult{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - b.tokens) / limit.Rate)
+	}
+
+	result.Remaining = int(math.Floor(b.tokens))
+	result.ResetAfter = secondsToDuration((float64(limit.Burst) - b.tokens) / limit.Rate)
+
+	return result, nil
+}
+
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate)
+		b.last = now
+	}
+}
+
+// sweep evicts full buckets at most once a minute; a full bucket behaves
+// exactly like a missing one.
+func (m *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(m.sweptAt) < time.Minute {
+		return
+	}
+	m.sweptAt = now
+
+	for key, b := range m.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
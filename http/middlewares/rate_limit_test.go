@@ -0,0 +1,112 @@
+package middlewares_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Lumicrate/gompose/http"
+	"github.com/Lumicrate/gompose/http/middlewares"
+)
+
+func TestMemoryRateLimitStoreRefill(t *testing.T) {
+	store := middlewares.NewMemoryRateLimitStore()
+	limit := middlewares.Limit{Rate: 2, Burst: 3} // a token every 500ms
+	start := time.Unix(1000, 0)
+
+	steps := []struct {
+		after      time.Duration // since start
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}{
+		{0, true, 2, 0},
+		{0, true, 1, 0},
+		{0, true, 0, 0},
+		{0, false, 0, 500 * time.Millisecond},
+		{250 * time.Millisecond, false, 0, 250 * time.Millisecond},
+		{500 * time.Millisecond, true, 0, 0},
+		{500 * time.Millisecond, false, 0, 500 * time.Millisecond},
+		// Refilling stops at the burst.
+		{time.Hour, true, 2, 0},
+	}
+
+	for i, s := range steps {
+		res, err := store.Take("k", limit, start.Add(s.after))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed != s.allowed || res.Remaining != s.remaining || res.RetryAfter != s.retryAfter {
+			t.Errorf("step %d at +%s: allowed %v, remaining %d, retry after %s; want %v, %d, %s",
+				i, s.after, res.Allowed, res.Remaining, res.RetryAfter, s.allowed, s.remaining, s.retryAfter)
+		}
+	}
+
+	// Keys have separate buckets.
+	if res, _ := store.Take("other", limit, start); !res.Allowed || res.Remaining != 2 {
+		t.Errorf("new key: allowed %v, remaining %d; want a full bucket", res.Allowed, res.Remaining)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	e := newEngine(middlewares.RateLimit(middlewares.RateLimitConfig{Requests: 2, Period: time.Minute}))
+	e.RegisterRoute("GET", "/items", ok, nil, false)
+
+	for i, want := range []string{"1", "0"} {
+		rec := serve(e, request("GET", "/items", nil, nil))
+		if rec.Code != 200 {
+			t.Fatalf("request %d: status %d, want 200", i+1, rec.Code)
+		}
+		if got := rec.Header().Get("RateLimit-Remaining"); got != want {
+			t.Errorf("request %d: RateLimit-Remaining = %s, want %s", i+1, got, want)
+		}
+		if got := rec.Header().Get("RateLimit-Policy"); got != "2;w=60;burst=2" {
+			t.Errorf("RateLimit-Policy = %s", got)
+		}
+	}
+
+	rec := serve(e, request("GET", "/items", nil, nil))
+	if rec.Code != 429 {
+		t.Fatalf("third request: status %d, want 429", rec.Code)
+	}
+	// One token every 30 seconds.
+	if got := rec.Header().Get("Retry-After"); got != "30" && got != "29" {
+		t.Errorf("Retry-After = %q, want 30", got)
+	}
+	if got := rec.Header().Get("RateLimit-Limit"); got != "2" {
+		t.Errorf("RateLimit-Limit = %q, want 2", got)
+	}
+
+	// Another client has its own bucket.
+	other := request("GET", "/items", nil, nil)
+	other.RemoteAddr = "203.0.113.9:1234"
+	if rec := serve(e, other); rec.Code != 200 {
+		t.Errorf("other client: status %d, want 200", rec.Code)
+	}
+}
+
+func TestRateLimitSkipAndKey(t *testing.T) {
+	e := newEngine(middlewares.RateLimit(middlewares.RateLimitConfig{
+		Requests: 1,
+		Period:   time.Minute,
+		Key:      middlewares.KeyByHeader("X-API-Key"),
+		Skip:     func(ctx http.Context) bool { return ctx.Path() == "/health" },
+	}))
+	e.RegisterRoute("GET", "/items", ok, nil, false)
+	e.RegisterRoute("GET", "/health", ok, nil, false)
+
+	tests := []struct {
+		name, path, key string
+		code            int
+	}{
+		{"first request of key a", "/items", "a", 200},
+		{"second request of key a", "/items", "a", 429},
+		{"key b from the same IP", "/items", "b", 200},
+		{"skipped path", "/health", "a", 200},
+	}
+	for _, tt := range tests {
+		rec := serve(e, request("GET", tt.path, nil, map[string]string{"X-API-Key": tt.key}))
+		if rec.Code != tt.code {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.code)
+		}
+	}
+}
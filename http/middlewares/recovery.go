@@ -0,0 +1,57 @@
+package middlewares
+
+import (
+	"fmt"
+	"log/slog"
+	nethttp "net/http"
+	"runtime/debug"
+
+	"github.com/Lumicrate/gompose/http"
+)
+
+// Recovery turns a panic in a later middleware, handler or entity hook into
+// a 500 response with the standard {"error": ...} body and logs it with the
+// stack trace and request ID. A nil logger uses slog.Default().
+//
+// It only relies on http.Context, so it behaves the same on every engine.
+// Register it after RequestID and AccessLog and before everything else.
+func Recovery(logger *slog.Logger) http.MiddlewareFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(ctx http.Context) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				// The server aborts the connection on purpose for this one.
+				if rec == nethttp.ErrAbortHandler {
+					panic(rec)
+				}
+
+				l := logger
+				if l == nil {
+					l = slog.Default()
+				}
+
+				attrs := []slog.Attr{
+					slog.String("error", fmt.Sprint(rec)),
+					slog.String("method", ctx.Method()),
+					slog.String("path", ctx.Path()),
+					slog.String("stack", string(debug.Stack())),
+				}
+				if id := RequestIDFrom(ctx); id != "" {
+					attrs = append(attrs, slog.String("request_id", id))
+				}
+				l.LogAttrs(ctx.Request().Context(), slog.LevelError, "panic recovered", attrs...)
+
+				// Nothing can be done if the handler already started the response.
+				if ctx.BytesWritten() == 0 {
+					ctx.JSON(500, map[string]string{"error": "internal server error"})
+				}
+				ctx.Abort()
+			}()
+
+			next(ctx)
+		}
+	}
+}
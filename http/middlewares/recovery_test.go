@@ -0,0 +1,65 @@
+package middlewares_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	nethttp "net/http"
+	"strings"
+	"testing"
+
+	"github.com/Lumicrate/gompose/http"
+	"github.com/Lumicrate/gompose/http/middlewares"
+)
+
+func TestRecovery(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	e := newEngine(middlewares.RequestID(), middlewares.Recovery(logger))
+	e.RegisterRoute("GET", "/panic", func(ctx http.Context) {
+		panic(errors.New("db password is hunter2"))
+	}, nil, false)
+	e.RegisterRoute("GET", "/partial", func(ctx http.Context) {
+		ctx.JSON(202, map[string]string{"status": "accepted"})
+		panic("after the response")
+	}, nil, false)
+
+	rec := serve(e, request("GET", "/panic", nil, map[string]string{middlewares.RequestIDHeader: "req-1"}))
+	if rec.Code != 500 {
+		t.Fatalf("status %d, want 500", rec.Code)
+	}
+	if got := strings.TrimSpace(rec.Body.String()); got != `{"error":"internal server error"}` {
+		t.Errorf("body %s", got)
+	}
+	if strings.Contains(rec.Body.String(), "hunter2") {
+		t.Error("the panic value leaked into the response")
+	}
+
+	// The details go to the log instead.
+	for _, want := range []string{"panic recovered", "hunter2", "request_id=req-1", "path=/panic", "stack="} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log does not contain %q: %s", want, logs.String())
+		}
+	}
+
+	// A response that was already started is left alone.
+	rec = serve(e, request("GET", "/partial", nil, nil))
+	if rec.Code != 202 || strings.Contains(rec.Body.String(), "internal server error") {
+		t.Errorf("status %d, body %s; want the handler's 202 response only", rec.Code, rec.Body)
+	}
+}
+
+func TestRecoveryRepanicsErrAbortHandler(t *testing.T) {
+	e := newEngine(middlewares.Recovery(slog.New(slog.DiscardHandler)))
+	e.RegisterRoute("GET", "/abort", func(ctx http.Context) {
+		panic(nethttp.ErrAbortHandler)
+	}, nil, false)
+
+	defer func() {
+		if rec := recover(); rec != nethttp.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", rec)
+		}
+	}()
+	serve(e, request("GET", "/abort", nil, nil))
+}
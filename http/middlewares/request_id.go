@@ -0,0 +1,46 @@
+package middlewares
+
+import (
+	"github.com/Lumicrate/gompose/http"
+	"github.com/Lumicrate/gompose/utils"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID propagates the X-Request-ID header of the request, or generates
+// one, stores it in the context under http.CtxRequestID and echoes it in the
+// response.
+func RequestID() http.MiddlewareFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(ctx http.Context) {
+			id := ctx.Header(RequestIDHeader)
+			if !validRequestID(id) {
+				id = utils.GenerateUUID()
+			}
+
+			ctx.Set(http.CtxRequestID, id)
+			ctx.SetHeader(RequestIDHeader, id)
+
+			next(ctx)
+		}
+	}
+}
+
+// RequestIDFrom returns the request ID of ctx, or "" if RequestID did not run.
+func RequestIDFrom(ctx http.Context) string {
+	id, _ := ctx.Get(http.CtxRequestID).(string)
+	return id
+}
+
+// validRequestID rejects IDs that are too long or could forge log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Lumicrate/gompose/http"
+)
+
+// ctxParentContext keeps the request context from before any timeout, so
+// that a route-level Timeout can be longer than a global one.
+const ctxParentContext = "timeout_parent_context"
+
+// Timeout gives the request context a deadline of d. The handler keeps
+// running, but everything that honours the context, such as the database
+// adapters, stops once it passes. If the handler has not written a response
+// by then, Timeout answers 504.
+func Timeout(d time.Duration) http.MiddlewareFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(ctx http.Context) {
+			parent, ok := ctx.Get(ctxParentContext).(context.Context)
+			if !ok {
+				parent = ctx.Request().Context()
+				ctx.Set(ctxParentContext, parent)
+			}
+
+			timeoutCtx, cancel := context.WithTimeout(parent, d)
+			defer cancel()
+
+			ctx.SetRequest(ctx.Request().WithContext(timeoutCtx))
+
+			next(ctx)
+
+			if errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) && ctx.BytesWritten() == 0 {
+				ctx.JSON(504, map[string]string{"error": "request timed out"})
+				ctx.Abort()
+			}
+		}
+	}
+}
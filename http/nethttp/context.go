@@ -0,0 +1,165 @@
+package nethttpadapter
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+)
+
+// NetHTTPContext implements http.Context on top of the standard library.
+// One instance serves the whole middleware chain of a request.
+type NetHTTPContext struct {
+	w       *responseWriter
+	r       *http.Request
+	route   string
+	values  map[string]any
+	aborted bool
+}
+
+func (n *NetHTTPContext) JSON(code int, obj any) {
+	n.w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	n.w.WriteHeader(code)
+	if code == http.StatusNoContent || code == http.StatusNotModified {
+		return
+	}
+	_ = json.NewEncoder(n.w).Encode(obj)
+}
+
+func (n *NetHTTPContext) Bind(obj any) error {
+	return n.BindJSON(obj)
+}
+
+func (n *NetHTTPContext) BindJSON(obj any) error {
+	return json.NewDecoder(n.r.Body).Decode(obj)
+}
+
+func (n *NetHTTPContext) Param(key string) string {
+	return n.r.PathValue(key)
+}
+
+func (n *NetHTTPContext) Query(key string) string {
+	return n.r.URL.Query().Get(key)
+}
+
+func (n *NetHTTPContext) QueryParams() map[string][]string {
+	return n.r.URL.Query()
+}
+
+func (n *NetHTTPContext) SetHeader(key, value string) {
+	n.w.Header().Set(key, value)
+}
+
+func (n *NetHTTPContext) AddHeader(key, value string) {
+	n.w.Header().Add(key, value)
+}
+
+func (n *NetHTTPContext) Method() string {
+	return n.r.Method
+}
+
+func (n *NetHTTPContext) Path() string {
+	return n.r.URL.Path
+}
+
+func (n *NetHTTPContext) Route() string {
+	return n.route
+}
+
+// SetStatus sets the status that is sent if nothing else is written.
+func (n *NetHTTPContext) SetStatus(code int) {
+	n.w.status = code
+}
+
+func (n *NetHTTPContext) Status() int {
+	return n.w.status
+}
+
+func (n *NetHTTPContext) BytesWritten() int {
+	return n.w.size
+}
+
+func (n *NetHTTPContext) RemoteIP() string {
+	host, _, err := net.SplitHostPort(n.r.RemoteAddr)
+	if err != nil {
+		return n.r.RemoteAddr
+	}
+	return host
+}
+
+func (n *NetHTTPContext) Header(header string) string {
+	return n.r.Header.Get(header)
+}
+
+func (n *NetHTTPContext) Body(content string) {
+	_, _ = n.w.Write([]byte(content))
+}
+
+// Abort marks the request as aborted. The chain already stops because the
+// middleware does not call next.
+func (n *NetHTTPContext) Abort() {
+	n.aborted = true
+}
+
+// Next is a no-op; middlewares call next(ctx).
+func (n *NetHTTPContext) Next() {}
+
+func (n *NetHTTPContext) Set(key string, value any) {
+	if n.values == nil {
+		n.values = make(map[string]any)
+	}
+	n.values[key] = value
+}
+
+func (n *NetHTTPContext) Get(key string) any {
+	return n.values[key]
+}
+
+func (n *NetHTTPContext) Request() *http.Request {
+	return n.r
+}
+
+func (n *NetHTTPContext) SetRequest(r *http.Request) {
+	n.r = r
+}
+
+// ResponseWriter returns the underlying writer, e.g. for streaming.
+func (n *NetHTTPContext) ResponseWriter() http.ResponseWriter {
+	return n.w
+}
+
+// finish sends a status set with SetStatus when the handler wrote nothing.
+func (n *NetHTTPContext) finish() {
+	if !n.w.wroteHeader {
+		n.w.WriteHeader(n.w.status)
+	}
+}
+
+// responseWriter records the status and the size of the response.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	size        int
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.status = code
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(w.status)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
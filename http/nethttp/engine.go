@@ -0,0 +1,127 @@
+package nethttpadapter
+
+import (
+	"context"
+	"errors"
+	nethttp "net/http"
+	"strings"
+	"sync"
+
+	"github.com/Lumicrate/gompose/http"
+)
+
+// NetHTTPEngine is an HTTPEngine built on the standard library's
+// http.ServeMux, using its method and wildcard patterns for routing.
+type NetHTTPEngine struct {
+	*http.Router
+
+	mux  *nethttp.ServeMux
+	port int
+
+	config http.ServerConfig
+
+	mu        sync.Mutex
+	server    *nethttp.Server
+	serverErr error
+}
+
+func New(port int) *NetHTTPEngine {
+	e := &NetHTTPEngine{
+		mux:  nethttp.NewServeMux(),
+		port: port,
+	}
+	e.Router = http.NewRouter(e.mount)
+
+	// "/" matches whatever no route does, so unmatched requests still run
+	// the global middlewares before the 404.
+	e.mux.HandleFunc("/", func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		serve(e.NotFound(), "", w, r)
+	})
+
+	return e
+}
+
+func (e *NetHTTPEngine) Init(_ int) error {
+	return nil
+}
+
+// Handler returns the engine as an http.Handler, to mount it on an
+// existing server or mux:
+//
+//	mux.Handle("/api/", nethttp.StripPrefix("/api", engine.Handler()))
+func (e *NetHTTPEngine) Handler() nethttp.Handler {
+	return e.mux
+}
+
+// SetServerConfig sets the bind address, TLS, timeouts and HTTP/2 options
+// used by Start. It must be called before Start.
+func (e *NetHTTPEngine) SetServerConfig(cfg http.ServerConfig) *NetHTTPEngine {
+	e.config = cfg
+	return e
+}
+
+func (e *NetHTTPEngine) Start() error {
+	server, err := e.httpServer()
+	if err != nil {
+		return err
+	}
+
+	err = e.config.Serve(server)
+	if errors.Is(err, nethttp.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func (e *NetHTTPEngine) Shutdown(ctx context.Context) error {
+	server, _ := e.httpServer()
+	return server.Shutdown(ctx)
+}
+
+func (e *NetHTTPEngine) httpServer() (*nethttp.Server, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.server == nil {
+		e.server = &nethttp.Server{Addr: e.config.Address(e.port), Handler: e.mux}
+		e.serverErr = e.config.Apply(e.server)
+	}
+	return e.server, e.serverErr
+}
+
+// mount registers a chain built by the router with the mux.
+func (e *NetHTTPEngine) mount(method, path string, handler http.HandlerFunc) {
+	e.mux.HandleFunc(method+" "+muxPattern(path), func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		serve(handler, path, w, r)
+	})
+}
+
+func serve(chain http.HandlerFunc, route string, w nethttp.ResponseWriter, r *nethttp.Request) {
+	ctx := &NetHTTPContext{
+		w:     &responseWriter{ResponseWriter: w, status: 200},
+		r:     r,
+		route: route,
+	}
+	chain(ctx)
+	ctx.finish()
+}
+
+// muxPattern converts gompose's ":id" and "*path" segments to ServeMux
+// wildcards. A trailing slash only matches itself, as it does in gin.
+func muxPattern(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		switch {
+		case strings.HasPrefix(s, ":"):
+			segments[i] = "{" + s[1:] + "}"
+		case strings.HasPrefix(s, "*"):
+			segments[i] = "{" + s[1:] + "...}"
+		}
+	}
+
+	pattern := strings.Join(segments, "/")
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "{$}"
+	}
+	return pattern
+}
@@ -0,0 +1,15 @@
+package nethttpadapter_test
+
+import (
+	"testing"
+
+	"github.com/Lumicrate/gompose/http"
+	"github.com/Lumicrate/gompose/http/enginetest"
+	nethttpadapter "github.com/Lumicrate/gompose/http/nethttp"
+)
+
+func TestEngine(t *testing.T) {
+	if err := enginetest.TestEngine(func() http.HTTPEngine { return nethttpadapter.New(0) }); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,124 @@
+package http
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MountFunc registers handler with an engine's framework for method and the
+// full gompose path, e.g. "/users/:id".
+type MountFunc func(method, path string, handler HandlerFunc)
+
+// Router is the route bookkeeping shared by the engine adapters: it keeps
+// the route list and the group middlewares, builds each route's chain and
+// answers OPTIONS. An adapter embeds it and supplies only a MountFunc.
+type Router struct {
+	root    *Group
+	mount   MountFunc
+	routes  []Route
+	methods map[string][]string // registered methods per path, for OPTIONS
+}
+
+func NewRouter(mount MountFunc) *Router {
+	r := &Router{
+		mount:   mount,
+		routes:  []Route{},
+		methods: make(map[string][]string),
+	}
+	r.root = &Group{router: r}
+	return r
+}
+
+func (r *Router) RegisterRoute(method string, path string, handler HandlerFunc, entity any, isProtected bool) {
+	r.root.RegisterRoute(method, path, handler, entity, isProtected)
+}
+
+func (r *Router) Use(middleware MiddlewareFunc) {
+	r.root.Use(middleware)
+}
+
+func (r *Router) Group(prefix string) RouteGroup {
+	return r.root.Group(prefix)
+}
+
+func (r *Router) Routes() []Route {
+	return r.routes
+}
+
+// NotFound returns a handler that runs the global middlewares and then
+// answers 404, for the engine's unmatched requests.
+func (r *Router) NotFound() HandlerFunc {
+	return Chain(r.root.middlewares, func(ctx Context) {
+		ctx.JSON(404, map[string]string{"error": "not found"})
+	})
+}
+
+func (r *Router) register(group *Group, method, path string, handler HandlerFunc, entity any, isProtected bool) {
+	switch method {
+	case "GET", "POST", "PUT", "PATCH", "DELETE":
+	default:
+		panic(fmt.Sprintf("Unsupported method: %s", method))
+	}
+
+	fullPath := group.prefix + path
+	r.routes = append(r.routes, Route{
+		Method:    method,
+		Path:      fullPath,
+		Entity:    entity,
+		Protected: isProtected,
+	})
+
+	// The chain is built once, from the middlewares added so far; each
+	// request gets a single Context that flows through all of it.
+	r.mount(method, fullPath, Chain(group.chain(), handler))
+
+	// Answer OPTIONS on every registered path with 204 and an Allow header,
+	// so that CORS preflights reach the middlewares instead of a 404. Its
+	// chain is built per request: CORS is often added after the routes.
+	if _, ok := r.methods[fullPath]; !ok {
+		allow := func(ctx Context) {
+			methods := append(append([]string{}, r.methods[fullPath]...), "OPTIONS")
+			ctx.SetHeader("Allow", strings.Join(methods, ", "))
+			ctx.SetStatus(204)
+		}
+		r.mount("OPTIONS", fullPath, func(ctx Context) {
+			Chain(group.chain(), allow)(ctx)
+		})
+	}
+	r.methods[fullPath] = append(r.methods[fullPath], method)
+}
+
+// IsPreflight reports whether the request is a CORS preflight. Browsers
+// send preflights without credentials, so authentication middlewares let
+// them through to the CORS middleware.
+func IsPreflight(ctx Context) bool {
+	return ctx.Method() == "OPTIONS" && ctx.Header("Access-Control-Request-Method") != ""
+}
+
+// Group is a RouteGroup with a path prefix and its own middlewares.
+type Group struct {
+	router      *Router
+	parent      *Group
+	prefix      string
+	middlewares []MiddlewareFunc
+}
+
+func (g *Group) RegisterRoute(method string, path string, handler HandlerFunc, entity any, isProtected bool) {
+	g.router.register(g, method, path, handler, entity, isProtected)
+}
+
+func (g *Group) Use(middleware MiddlewareFunc) {
+	g.middlewares = append(g.middlewares, middleware)
+}
+
+func (g *Group) Group(prefix string) RouteGroup {
+	return &Group{router: g.router, parent: g, prefix: g.prefix + prefix}
+}
+
+// chain returns the middlewares of the group's ancestors and then its own.
+func (g *Group) chain() []MiddlewareFunc {
+	if g.parent == nil {
+		return append([]MiddlewareFunc{}, g.middlewares...)
+	}
+	return append(g.parent.chain(), g.middlewares...)
+}
@@ -0,0 +1,202 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ServerConfig configures how an engine listens. The zero value serves
+// plain HTTP/1.1 on ":<port>" without timeouts, as before.
+type ServerConfig struct {
+	Addr string // host:port to bind, defaults to ":<port>" of the engine
+
+	// TLSCertFile and TLSKeyFile enable HTTPS. The files are reloaded when
+	// they change, so rotated certificates are picked up without a restart.
+	TLSCertFile string
+	TLSKeyFile  string
+	// ClientCAFile enables mutual TLS: client certificates must be signed
+	// by one of its CAs.
+	ClientCAFile string
+	ClientAuth   tls.ClientAuthType // defaults to RequireAndVerifyClientCert with ClientCAFile
+	MinVersion   uint16             // defaults to TLS 1.2
+
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+
+	// H2C serves HTTP/2 over plain TCP (prior knowledge), e.g. behind a
+	// service mesh that terminates TLS. HTTP/2 is always on with TLS.
+	H2C bool
+}
+
+func (c ServerConfig) Address(port int) string {
+	if c.Addr != "" {
+		return c.Addr
+	}
+	return fmt.Sprintf(":%d", port)
+}
+
+func (c ServerConfig) TLSEnabled() bool {
+	return c.TLSCertFile != "" || c.TLSKeyFile != ""
+}
+
+// TLSConfig builds the TLS configuration, or returns nil when TLS is off.
+func (c ServerConfig) TLSConfig() (*tls.Config, error) {
+	if !c.TLSEnabled() {
+		if c.ClientCAFile != "" {
+			return nil, errors.New("http: ClientCAFile requires TLSCertFile and TLSKeyFile")
+		}
+		return nil, nil
+	}
+	if c.TLSCertFile == "" || c.TLSKeyFile == "" {
+		return nil, errors.New("http: both TLSCertFile and TLSKeyFile must be set")
+	}
+
+	reloader, err := NewCertReloader(c.TLSCertFile, c.TLSKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		GetCertificate: reloader.GetCertificate,
+		MinVersion:     c.MinVersion,
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("http: reading client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("http: no certificates found in %s", c.ClientCAFile)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = c.ClientAuth
+		if cfg.ClientAuth == tls.NoClientCert {
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	return cfg, nil
+}
+
+// Apply sets the timeouts, header limit, TLS and protocols on srv.
+func (c ServerConfig) Apply(srv *http.Server) error {
+	srv.ReadTimeout = c.ReadTimeout
+	srv.ReadHeaderTimeout = c.ReadHeaderTimeout
+	srv.WriteTimeout = c.WriteTimeout
+	srv.IdleTimeout = c.IdleTimeout
+	srv.MaxHeaderBytes = c.MaxHeaderBytes
+
+	tlsConfig, err := c.TLSConfig()
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		// Advertise HTTP/2 even when the TLS listener is created outside
+		// ListenAndServeTLS, as echo does.
+		tlsConfig.NextProtos = []string{"h2", "http/1.1"}
+	}
+	srv.TLSConfig = tlsConfig
+
+	if c.H2C {
+		protocols := new(http.Protocols)
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+		srv.Protocols = protocols
+	}
+	return nil
+}
+
+// Serve listens on srv.Addr with TLS if it is configured.
+func (c ServerConfig) Serve(srv *http.Server) error {
+	if srv.TLSConfig != nil {
+		return srv.ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServe()
+}
+
+// CertReloader serves a certificate from files and reloads it when they
+// change. It checks the files at most once a second.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checkedAt) >= time.Second {
+		r.checkedAt = time.Now()
+		if modTime, err := r.latestModTime(); err == nil && modTime.After(r.modTime) {
+			// Keep serving the old certificate if the new files are
+			// incomplete, e.g. halfway through a rotation.
+			_ = r.reloadLocked()
+		}
+	}
+	return r.cert, nil
+}
+
+func (r *CertReloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reloadLocked()
+}
+
+func (r *CertReloader) reloadLocked() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("http: loading TLS certificate: %w", err)
+	}
+
+	r.cert = &cert
+	r.modTime = modTime
+	r.checkedAt = time.Now()
+	return nil
+}
+
+func (r *CertReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, f := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("http: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
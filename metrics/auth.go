@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Lumicrate/gompose/auth"
+	"github.com/Lumicrate/gompose/http"
+)
+
+const ctxAuthPassed = "metrics_auth_passed"
+
+type instrumentedAuth struct {
+	auth.AuthProvider
+	m    *Metrics
+	name string
+}
+
+// WrapAuth returns a provider that counts the authentication results of
+// protected requests and of its login routes. The wrapper still implements
+// auth.Authenticator and auth.SecurityDescriber when provider does, so it
+// can be chained and documented like the original.
+func (m *Metrics) WrapAuth(provider auth.AuthProvider) auth.AuthProvider {
+	p := &instrumentedAuth{AuthProvider: provider, m: m, name: fmt.Sprintf("%T", provider)}
+
+	authenticator, isAuthenticator := provider.(auth.Authenticator)
+	describer, isDescriber := provider.(auth.SecurityDescriber)
+	if isAuthenticator {
+		p.name = authenticator.Name()
+	}
+
+	switch {
+	case isAuthenticator && isDescriber:
+		return struct {
+			*instrumentedAuth
+			auth.Authenticator
+			auth.SecurityDescriber
+		}{p, authenticator, describer}
+	case isAuthenticator:
+		return struct {
+			*instrumentedAuth
+			auth.Authenticator
+		}{p, authenticator}
+	case isDescriber:
+		return struct {
+			*instrumentedAuth
+			auth.SecurityDescriber
+		}{p, describer}
+	}
+	return p
+}
+
+func (p *instrumentedAuth) Middleware() http.MiddlewareFunc {
+	inner := p.AuthProvider.Middleware()
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		h := inner(func(ctx http.Context) {
+			ctx.Set(ctxAuthPassed, true)
+			next(ctx)
+		})
+
+		return func(ctx http.Context) {
+			h(ctx)
+
+			result := "failure"
+			if ctx.Get(ctxAuthPassed) == true {
+				result = "success"
+			}
+			p.m.authChecks.WithLabelValues(p.name, result).Inc()
+		}
+	}
+}
+
+func (p *instrumentedAuth) RegisterRoutes(engine http.HTTPEngine) {
+	p.AuthProvider.RegisterRoutes(&loginRecorder{HTTPEngine: engine, p: p})
+}
+
+// loginRecorder counts the results of the login routes a provider
+// registers: "/login" and the second factor of a two-factor login.
+type loginRecorder struct {
+	http.HTTPEngine
+	p *instrumentedAuth
+}
+
+func (r *loginRecorder) RegisterRoute(method, path string, handler http.HandlerFunc, entity any, isProtected bool) {
+	if strings.HasSuffix(path, "/login") || strings.HasSuffix(path, "/2fa/verify") {
+		next := handler
+		handler = func(ctx http.Context) {
+			next(ctx)
+			r.p.m.authLogins.WithLabelValues(r.p.name, loginResult(ctx.Status())).Inc()
+		}
+	}
+	r.HTTPEngine.RegisterRoute(method, path, handler, entity, isProtected)
+}
+
+func loginResult(status int) string {
+	switch {
+	case status < 400:
+		return "success"
+	case status == 429:
+		return "throttled"
+	default:
+		return "failure"
+	}
+}
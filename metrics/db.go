@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/Lumicrate/gompose/db"
+)
+
+type instrumentedDB struct {
+	db.DBAdapter
+	m *Metrics
+}
+
+// WrapDB returns an adapter that records the latency and errors of every
+// operation of adapter, labelled by entity type and operation. The wrapper
+// forwards WithContext, so request cancellation still reaches the database.
+func (m *Metrics) WrapDB(adapter db.DBAdapter) db.DBAdapter {
+	return &instrumentedDB{DBAdapter: adapter, m: m}
+}
+
+func (d *instrumentedDB) WithContext(ctx context.Context) db.DBAdapter {
+	return &instrumentedDB{DBAdapter: db.WithContext(d.DBAdapter, ctx), m: d.m}
+}
+
+func (d *instrumentedDB) Create(entity any) error {
+	start := time.Now()
+	return d.track(entity, "create", start, d.DBAdapter.Create(entity))
+}
+
+func (d *instrumentedDB) Update(entity any) error {
+	start := time.Now()
+	return d.track(entity, "update", start, d.DBAdapter.Update(entity))
+}
+
+func (d *instrumentedDB) Delete(id string, entity any) error {
+	start := time.Now()
+	return d.track(entity, "delete", start, d.DBAdapter.Delete(id, entity))
+}
+
+func (d *instrumentedDB) UpdateWhere(entity any, filters map[string]any, values map[string]any) (int64, error) {
+	start := time.Now()
+	n, err := d.DBAdapter.UpdateWhere(entity, filters, values)
+	return n, d.track(entity, "update_where", start, err)
+}
+
+func (d *instrumentedDB) FindAll(entity any, filters map[string]any, pagination db.Pagination, sort []db.Sort) (any, error) {
+	start := time.Now()
+	result, err := d.DBAdapter.FindAll(entity, filters, pagination, sort)
+	return result, d.track(entity, "find_all", start, err)
+}
+
+func (d *instrumentedDB) FindByID(id string, entity any) (any, error) {
+	start := time.Now()
+	result, err := d.DBAdapter.FindByID(id, entity)
+	return result, d.track(entity, "find_by_id", start, err)
+}
+
+func (d *instrumentedDB) track(entity any, operation string, start time.Time, err error) error {
+	name := entityName(entity)
+	d.m.dbDuration.WithLabelValues(name, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		d.m.dbErrors.WithLabelValues(name, operation).Inc()
+	}
+	return err
+}
+
+func entityName(entity any) string {
+	t := reflect.TypeOf(entity)
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || t.Name() == "" {
+		return "unknown"
+	}
+	return t.Name()
+}
@@ -0,0 +1,136 @@
+// Package metrics instruments a gompose application with Prometheus
+// metrics: HTTP requests through a middleware, database operations through
+// a db.DBAdapter wrapper and authentication through an auth.AuthProvider
+// wrapper. core.App.UseMetrics wires all three and serves /metrics.
+package metrics
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+
+	"github.com/Lumicrate/gompose/http"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/common/expfmt"
+)
+
+const namespace = "gompose"
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	dbDuration   *prometheus.HistogramVec
+	dbErrors     *prometheus.CounterVec
+	authChecks   *prometheus.CounterVec
+	authLogins   *prometheus.CounterVec
+}
+
+// New returns a Metrics with its own registry, which also collects Go
+// runtime and process metrics.
+func New() *Metrics {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return NewWithRegistry(registry)
+}
+
+// NewWithRegistry registers the gompose metrics in registry, e.g. to expose
+// them alongside an application's own collectors.
+func NewWithRegistry(registry *prometheus.Registry) *Metrics {
+	m := &Metrics{
+		registry: registry,
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route template and status.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route template and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		dbDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_operation_duration_seconds",
+			Help:      "Database operation latency by entity and operation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"entity", "operation"}),
+		dbErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_operation_errors_total",
+			Help:      "Failed database operations by entity and operation.",
+		}, []string{"entity", "operation"}),
+		authChecks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "auth_checks_total",
+			Help:      "Authentication of protected requests by provider and result.",
+		}, []string{"provider", "result"}),
+		authLogins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "auth_logins_total",
+			Help:      "Login attempts by provider and result.",
+		}, []string{"provider", "result"}),
+	}
+
+	registry.MustRegister(m.httpRequests, m.httpDuration, m.dbDuration, m.dbErrors, m.authChecks, m.authLogins)
+	return m
+}
+
+// Registry returns the registry the metrics are exposed from, to register
+// application collectors.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Middleware records the count and latency of every request. Requests that
+// match no route are labelled with route "unmatched", so that scanners
+// cannot create unbounded series.
+func (m *Metrics) Middleware() http.MiddlewareFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(ctx http.Context) {
+			start := time.Now()
+			next(ctx)
+
+			route := ctx.Route()
+			if route == "" {
+				route = "unmatched"
+			}
+			status := strconv.Itoa(ctx.Status())
+
+			m.httpRequests.WithLabelValues(ctx.Method(), route, status).Inc()
+			m.httpDuration.WithLabelValues(ctx.Method(), route, status).Observe(time.Since(start).Seconds())
+		}
+	}
+}
+
+// Handler serves the metrics in the Prometheus text exposition format.
+func (m *Metrics) Handler() http.HandlerFunc {
+	format := expfmt.NewFormat(expfmt.TypeTextPlain)
+
+	return func(ctx http.Context) {
+		families, err := m.registry.Gather()
+		if err != nil && len(families) == 0 {
+			ctx.JSON(500, map[string]string{"error": "failed to gather metrics"})
+			return
+		}
+
+		var buf bytes.Buffer
+		encoder := expfmt.NewEncoder(&buf, format)
+		for _, family := range families {
+			if err := encoder.Encode(family); err != nil {
+				ctx.JSON(500, map[string]string{"error": "failed to encode metrics"})
+				return
+			}
+		}
+
+		ctx.SetHeader("Content-Type", string(format))
+		ctx.SetStatus(200)
+		ctx.Body(buf.String())
+	}
+}
@@ -0,0 +1,124 @@
+package tracing
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/Lumicrate/gompose/db"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracedDB struct {
+	db.DBAdapter
+	t   *Tracing
+	ctx context.Context
+}
+
+// WrapDB returns an adapter that records a client span for every operation
+// of adapter, with the entity, the operation and the number of rows
+// returned. Spans are children of the context passed to WithContext, which
+// the crud handlers set to the request context.
+func (t *Tracing) WrapDB(adapter db.DBAdapter) db.DBAdapter {
+	return &tracedDB{DBAdapter: adapter, t: t, ctx: context.Background()}
+}
+
+func (d *tracedDB) WithContext(ctx context.Context) db.DBAdapter {
+	return &tracedDB{DBAdapter: d.DBAdapter, t: d.t, ctx: ctx}
+}
+
+func (d *tracedDB) Create(entity any) error {
+	adapter, span := d.start(entity, "create")
+	err := adapter.Create(entity)
+	d.end(span, 1, err)
+	return err
+}
+
+func (d *tracedDB) Update(entity any) error {
+	adapter, span := d.start(entity, "update")
+	err := adapter.Update(entity)
+	d.end(span, 1, err)
+	return err
+}
+
+func (d *tracedDB) Delete(id string, entity any) error {
+	adapter, span := d.start(entity, "delete")
+	err := adapter.Delete(id, entity)
+	d.end(span, 1, err)
+	return err
+}
+
+func (d *tracedDB) UpdateWhere(entity any, filters map[string]any, values map[string]any) (int64, error) {
+	adapter, span := d.start(entity, "update_where")
+	n, err := adapter.UpdateWhere(entity, filters, values)
+	d.end(span, int(n), err)
+	return n, err
+}
+
+func (d *tracedDB) FindAll(entity any, filters map[string]any, pagination db.Pagination, sort []db.Sort) (any, error) {
+	adapter, span := d.start(entity, "find_all")
+	result, err := adapter.FindAll(entity, filters, pagination, sort)
+	d.end(span, rowCount(result), err)
+	return result, err
+}
+
+func (d *tracedDB) FindByID(id string, entity any) (any, error) {
+	adapter, span := d.start(entity, "find_by_id")
+	result, err := adapter.FindByID(id, entity)
+	d.end(span, rowCount(result), err)
+	return result, err
+}
+
+// start opens the span and binds the wrapped adapter to it, so that spans
+// created by the driver, e.g. GORM plugins, nest below it.
+func (d *tracedDB) start(entity any, operation string) (db.DBAdapter, trace.Span) {
+	name := EntityName(entity)
+	ctx, span := d.t.tracer.Start(d.ctx, operation+" "+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			EntityKey.String(name),
+			semconv.DBOperationNameKey.String(operation),
+		),
+	)
+	return db.WithContext(d.DBAdapter, ctx), span
+}
+
+func (d *tracedDB) end(span trace.Span, rows int, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(semconv.DBResponseReturnedRows(rows))
+	}
+	span.End()
+}
+
+func rowCount(result any) int {
+	v := reflect.ValueOf(result)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		return v.Len()
+	case reflect.Invalid:
+		return 0
+	case reflect.Pointer:
+		return 0
+	}
+	return 1
+}
+
+// EntityName returns the name of the entity type, for span names and
+// attributes.
+func EntityName(entity any) string {
+	t := reflect.TypeOf(entity)
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || t.Name() == "" {
+		return "unknown"
+	}
+	return t.Name()
+}
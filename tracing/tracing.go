@@ -0,0 +1,84 @@
+// Package tracing adds OpenTelemetry spans to a gompose application: a
+// server span per request, continued from an incoming W3C traceparent, and
+// child spans for database operations. The crud handlers add spans for
+// entity hooks on their own. core.App.UseTracing wires everything.
+package tracing
+
+import (
+	"github.com/Lumicrate/gompose/http"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the spans gompose creates.
+const ScopeName = "github.com/Lumicrate/gompose"
+
+// EntityKey is the span attribute naming the entity type of a CRUD route,
+// hook or database operation.
+const EntityKey = attribute.Key("gompose.entity")
+
+type Tracing struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// New traces with provider, or with the global provider if it is nil. In
+// tests, pass an sdktrace.TracerProvider with a tracetest.InMemoryExporter.
+func New(provider trace.TracerProvider) *Tracing {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return &Tracing{
+		tracer:     provider.Tracer(ScopeName),
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+	}
+}
+
+// SetPropagator replaces the default W3C trace context and baggage
+// propagator.
+func (t *Tracing) SetPropagator(p propagation.TextMapPropagator) *Tracing {
+	t.propagator = p
+	return t
+}
+
+// Middleware starts a server span for every request, named after the
+// method and route template, and stores it in the request context so that
+// handlers, hooks and database operations create child spans.
+func (t *Tracing) Middleware() http.MiddlewareFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(ctx http.Context) {
+			r := ctx.Request()
+			parent := t.propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			name := ctx.Method()
+			attrs := []attribute.KeyValue{
+				semconv.HTTPRequestMethodKey.String(ctx.Method()),
+				semconv.URLPath(ctx.Path()),
+				semconv.ClientAddress(ctx.RemoteIP()),
+			}
+			if route := ctx.Route(); route != "" {
+				name += " " + route
+				attrs = append(attrs, semconv.HTTPRoute(route))
+			}
+
+			spanCtx, span := t.tracer.Start(parent, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(attrs...),
+			)
+			defer span.End()
+
+			ctx.SetRequest(r.WithContext(spanCtx))
+			next(ctx)
+
+			status := ctx.Status()
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= 500 {
+				span.SetStatus(codes.Error, "")
+			}
+		}
+	}
+}
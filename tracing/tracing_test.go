@@ -0,0 +1,120 @@
+package tracing_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Lumicrate/gompose/core"
+	"github.com/Lumicrate/gompose/db/sqlite"
+	ginadapter "github.com/Lumicrate/gompose/http/gin"
+	"github.com/Lumicrate/gompose/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type Note struct {
+	ID   string `gorm:"primaryKey" json:"id"`
+	Text string `json:"text"`
+}
+
+func (n *Note) BeforeCreate() error { return nil }
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	app := core.NewApp().
+		UseDB(sqlite.NewMemory()).
+		UseHTTP(ginadapter.New(0)).
+		UseTracing(tracing.New(provider)).
+		AddEntity(&Note{})
+	t.Cleanup(func() { _ = app.Shutdown(t.Context()) })
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest("POST", "/notes", strings.NewReader(`{"id":"1","text":"hello"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Traceparent", traceparent)
+	rec := httptest.NewRecorder()
+	app.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST /notes: status %d, body %s", rec.Code, rec.Body)
+	}
+
+	spans := exporter.GetSpans()
+	byName := map[string]tracetest.SpanStub{}
+	for _, s := range spans {
+		byName[s.Name] = s
+	}
+
+	server, ok := byName["POST /notes"]
+	if !ok {
+		t.Fatalf("no request span; got %v", names(spans))
+	}
+	if server.SpanKind != trace.SpanKindServer {
+		t.Errorf("request span kind = %v, want server", server.SpanKind)
+	}
+	if got := server.Parent.SpanID().String(); got != "00f067aa0ba902b7" || !server.Parent.IsRemote() {
+		t.Errorf("request span parent = %s (remote %v), want the incoming traceparent", got, server.Parent.IsRemote())
+	}
+	if got := server.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("request span trace ID = %s, want the incoming one", got)
+	}
+	expectAttr(t, server, "http.request.method", attribute.StringValue("POST"))
+	expectAttr(t, server, "http.route", attribute.StringValue("/notes"))
+	expectAttr(t, server, "http.response.status_code", attribute.IntValue(http.StatusCreated))
+
+	hook, ok := byName["hook BeforeCreate"]
+	if !ok {
+		t.Fatalf("no hook span; got %v", names(spans))
+	}
+	expectChild(t, hook, server)
+	expectAttr(t, hook, string(tracing.EntityKey), attribute.StringValue("Note"))
+	expectAttr(t, hook, "gompose.hook", attribute.StringValue("BeforeCreate"))
+
+	create, ok := byName["create Note"]
+	if !ok {
+		t.Fatalf("no database span; got %v", names(spans))
+	}
+	expectChild(t, create, server)
+	if create.SpanKind != trace.SpanKindClient {
+		t.Errorf("database span kind = %v, want client", create.SpanKind)
+	}
+	expectAttr(t, create, string(tracing.EntityKey), attribute.StringValue("Note"))
+	expectAttr(t, create, "db.operation.name", attribute.StringValue("create"))
+	expectAttr(t, create, "db.response.returned_rows", attribute.IntValue(1))
+}
+
+func expectChild(t *testing.T, child, parent tracetest.SpanStub) {
+	t.Helper()
+	if child.Parent.SpanID() != parent.SpanContext.SpanID() {
+		t.Errorf("span %q: parent %s, want %q (%s)", child.Name, child.Parent.SpanID(), parent.Name, parent.SpanContext.SpanID())
+	}
+	if child.SpanContext.TraceID() != parent.SpanContext.TraceID() {
+		t.Errorf("span %q is in another trace than %q", child.Name, parent.Name)
+	}
+}
+
+func expectAttr(t *testing.T, span tracetest.SpanStub, key string, want attribute.Value) {
+	t.Helper()
+	for _, kv := range span.Attributes {
+		if string(kv.Key) == key {
+			if kv.Value != want {
+				t.Errorf("span %q: %s = %v, want %v", span.Name, key, kv.Value.Emit(), want.Emit())
+			}
+			return
+		}
+	}
+	t.Errorf("span %q has no attribute %s", span.Name, key)
+}
+
+func names(spans tracetest.SpanStubs) []string {
+	var out []string
+	for _, s := range spans {
+		out = append(out, s.Name)
+	}
+	return out
+}
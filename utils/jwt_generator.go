@@ -7,13 +7,22 @@ import (
 )
 
 func GenerateJWT(userID, secretKey string, exp time.Duration) (string, error) {
+	return GenerateJWTWithClaims(userID, secretKey, exp, nil)
+}
+
+// GenerateJWTWithClaims is like GenerateJWT but adds extra claims to the token.
+func GenerateJWTWithClaims(userID, secretKey string, exp time.Duration, extra map[string]any) (string, error) {
 	if exp < 1 {
 		exp = time.Hour * 24
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": userID,
-		"exp": time.Now().Add(exp).Unix(),
-	})
+	claims := jwt.MapClaims{}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	claims["sub"] = userID
+	claims["exp"] = time.Now().Add(exp).Unix()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString([]byte(secretKey))
 	if err != nil {
 		return "", fmt.Errorf("failed to generate token: %w", err)